@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// benchmarkPollInterval is how often the server status is checked while a
+// news server speed test is running
+const benchmarkPollInterval = time.Second
+
+// TimeOfDay is a local wall-clock time, configured as 'HH:MM'
+type TimeOfDay struct {
+	Hour   int
+	Minute int
+}
+
+func (t *TimeOfDay) UnmarshalFlag(value string) error {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return fmt.Errorf("invalid time of day %q, must be HH:MM", value)
+	}
+	t.Hour = parsed.Hour()
+	t.Minute = parsed.Minute()
+	return nil
+}
+
+// next returns the first occurrence of the time of day after now
+func (t TimeOfDay) next(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour, t.Minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+type benchmarkResult struct {
+	name     string
+	bytes    int64
+	duration time.Duration
+	lastRun  time.Time
+	success  bool
+}
+
+type DiskSpeedResult struct {
+	SizeMB     int64
+	DurationMS int64
+}
+
+// RunBenchmarks runs the disk and news server speed tests at each of the
+// configured times of day, forever
+func (c *NZBGetCollector) RunBenchmarks() {
+	schedule := c.Config.BenchmarkAt
+	for {
+		now := time.Now()
+		var next []time.Time
+		for _, at := range schedule {
+			next = append(next, at.next(now))
+		}
+		sort.Slice(next, func(i, j int) bool { return next[i].Before(next[j]) })
+
+		log.WithField("at", next[0].Format(time.RFC3339)).Debug("next benchmark")
+		time.Sleep(time.Until(next[0]))
+		c.runBenchmarks()
+	}
+}
+
+func (c *NZBGetCollector) runBenchmarks() {
+	// NZBGet may have been upgraded or downgraded since the last scrape
+	_, err := c.fetchVersion()
+	if err != nil {
+		log.WithError(err).Error("api get version")
+		return
+	}
+	if !c.HasFeature(FeatureBenchmarks) {
+		log.Warn("nzbget version doesn't support benchmarks, skipping")
+		return
+	}
+
+	var config NZBGetConfig
+	err = c.getApi("config", &config)
+	if err != nil {
+		log.WithError(err).Error("api get config")
+		return
+	}
+
+	if !c.benchmarkIdle() {
+		log.Info("downloads active, skipping benchmarks")
+		return
+	}
+	c.benchmarkDisk(&config)
+
+	if c.Config.BenchmarkNZB == "" {
+		return
+	}
+	// Test one server at a time so they don't compete for bandwidth
+	for idx, server := range config.Server {
+		if server.Name == "" || !server.Active {
+			continue
+		}
+		if !c.benchmarkIdle() {
+			log.Info("downloads active, skipping remaining benchmarks")
+			return
+		}
+		c.benchmarkServer(idx+1, server.Name)
+	}
+}
+
+// benchmarkIdle returns true if NZBGet isn't currently downloading anything
+func (c *NZBGetCollector) benchmarkIdle() bool {
+	var status Status
+	err := c.getApi("status", &status)
+	if err != nil {
+		log.WithError(err).Error("api get status")
+		return false
+	}
+	return status.ServerStandBy
+}
+
+func (c *NZBGetCollector) benchmarkDisk(config *NZBGetConfig) {
+	dir := c.Config.BenchmarkDiskDir
+	if dir == "" {
+		dir = config.InterDir
+	}
+	if dir == "" {
+		dir = config.DestDir
+	}
+	logger := log.WithField("dir", dir)
+
+	result := &benchmarkResult{name: dir, lastRun: time.Now()}
+	var speed DiskSpeedResult
+	err := c.callApi("testdiskspeed", []interface{}{
+		dir,
+		config.WriteBuffer,
+		c.Config.BenchmarkDiskSize,
+		int(c.Config.BenchmarkTimeout.Seconds()),
+	}, &speed)
+	if err != nil {
+		logger.WithError(err).Error("api call testdiskspeed")
+	} else {
+		result.success = true
+		result.bytes = speed.SizeMB * 1024 * 1024
+		result.duration = time.Duration(speed.DurationMS) * time.Millisecond
+		logger.WithField("duration", result.duration).Info("disk benchmark complete")
+	}
+
+	c.mu.Lock()
+	c.diskBenchmark = result
+	c.mu.Unlock()
+}
+
+// benchmarkServer starts a download speed test against a single news server
+// and measures the data downloaded until the test download leaves the queue.
+// testserverspeed doesn't return the id of the test download, so it is taken
+// to be the only download of the benchmark nzb url with a higher id than
+// anything in the queue or history before the test started, as NZBGet hands
+// out ids in increasing order. No other download is ever edited
+func (c *NZBGetCollector) benchmarkServer(idx int, name string) {
+	logger := log.WithField("server", name)
+	result := &benchmarkResult{name: name, lastRun: time.Now()}
+	defer func() {
+		c.mu.Lock()
+		c.serverBenchmarks[idx] = result
+		c.mu.Unlock()
+	}()
+
+	var before Status
+	err := c.getApi("status", &before)
+	if err != nil {
+		logger.WithError(err).Error("api get status")
+		return
+	}
+	lastID, err := c.lastNZBID()
+	if err != nil {
+		logger.WithError(err).Error("api get last nzbid")
+		return
+	}
+
+	var started bool
+	start := time.Now()
+	err = c.callApi("testserverspeed", []interface{}{c.Config.BenchmarkNZB, idx}, &started)
+	if err != nil {
+		logger.WithError(err).Error("api call testserverspeed")
+		return
+	}
+	if !started {
+		logger.Error("testserverspeed was not started")
+		return
+	}
+
+	// The test is complete once the test download has left the queue. The
+	// download may finish before the first poll, in which case it is looked
+	// for in the history instead
+	var testID int
+	deadline := start.Add(c.Config.BenchmarkTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(benchmarkPollInterval)
+
+		var status Status
+		err = c.getApi("status", &status)
+		if err != nil {
+			logger.WithError(err).Error("api get status")
+			return
+		}
+		var queue []Group
+		err = c.getApi("listgroups", &queue)
+		if err != nil {
+			logger.WithError(err).Error("api get listgroups")
+			return
+		}
+
+		var queued []int
+		for _, g := range queue {
+			if g.NZBID > lastID && g.URL == c.Config.BenchmarkNZB {
+				queued = append(queued, g.NZBID)
+			}
+		}
+		var done bool
+		switch {
+		case testID != 0:
+			done = !slices.Contains(queued, testID)
+		case len(queued) == 1:
+			testID = queued[0]
+		case len(queued) == 0:
+			done, err = c.benchmarkInHistory(lastID)
+			if err != nil {
+				logger.WithError(err).Error("api get history")
+				return
+			}
+		default:
+			// More than one new download of the benchmark nzb is ambiguous,
+			// so neither is picked, nor deleted on timeout
+		}
+
+		if done {
+			downloaded := status.DownloadedSize - before.DownloadedSize
+			if downloaded <= 0 {
+				logger.Warn("server benchmark downloaded nothing")
+				return
+			}
+			result.success = true
+			result.bytes = downloaded
+			result.duration = time.Since(start)
+			logger.WithField("duration", result.duration).Info("server benchmark complete")
+			return
+		}
+	}
+
+	logger.Warn("server benchmark timed out")
+	if testID == 0 {
+		return
+	}
+	// Remove the test download so that it doesn't block the remaining
+	// benchmarks, or use up quota
+	var deleted bool
+	err = c.callApi("editqueue", []interface{}{"GroupFinalDelete", "", []int{testID}}, &deleted)
+	if err != nil {
+		logger.WithError(err).Error("api call editqueue")
+	} else if !deleted {
+		logger.WithField("nzbid", testID).Error("timed out benchmark download was not deleted")
+	}
+}
+
+// lastNZBID returns the highest id of anything in the queue or history,
+// including hidden history records
+func (c *NZBGetCollector) lastNZBID() (int, error) {
+	var queue []Group
+	err := c.getApi("listgroups", &queue)
+	if err != nil {
+		return 0, err
+	}
+	var history []History
+	err = c.callApi("history", []interface{}{true}, &history)
+	if err != nil {
+		return 0, err
+	}
+
+	var last int
+	for _, g := range queue {
+		last = max(last, g.NZBID)
+	}
+	for _, hi := range history {
+		last = max(last, int(hi.NZBID))
+	}
+	return last, nil
+}
+
+// benchmarkInHistory returns true if exactly one download of the benchmark
+// nzb with an id higher than lastID has reached the history
+func (c *NZBGetCollector) benchmarkInHistory(lastID int) (bool, error) {
+	var history []History
+	err := c.callApi("history", []interface{}{true}, &history)
+	if err != nil {
+		return false, err
+	}
+
+	var found int
+	for _, hi := range history {
+		if int(hi.NZBID) > lastID && hi.URL == c.Config.BenchmarkNZB {
+			found++
+		}
+	}
+	return found == 1, nil
+}
+
+func (r *benchmarkResult) throughput() float64 {
+	if !r.success || r.duration <= 0 {
+		return 0
+	}
+	return float64(r.bytes) / r.duration.Seconds()
+}
+
+func (c *NZBGetCollector) collectBenchmarks(metrics chan<- prom.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r := c.diskBenchmark; r != nil {
+		metrics <- prom.MustNewConstMetric(c.benchmarkDiskThroughput, prom.GaugeValue, r.throughput(), r.name)
+		metrics <- prom.MustNewConstMetric(c.benchmarkDiskDuration, prom.GaugeValue, r.duration.Seconds(), r.name)
+		metrics <- prom.MustNewConstMetric(c.benchmarkDiskLastRun, prom.GaugeValue, float64(r.lastRun.Unix()), r.name)
+		metrics <- prom.MustNewConstMetric(c.benchmarkDiskSuccess, prom.GaugeValue, floatOf(r.success), r.name)
+	}
+	for idx, r := range c.serverBenchmarks {
+		id := fmt.Sprintf("%d", idx)
+		metrics <- prom.MustNewConstMetric(c.benchmarkServerThroughput, prom.GaugeValue, r.throughput(), id, r.name)
+		metrics <- prom.MustNewConstMetric(c.benchmarkServerDuration, prom.GaugeValue, r.duration.Seconds(), id, r.name)
+		metrics <- prom.MustNewConstMetric(c.benchmarkServerLastRun, prom.GaugeValue, float64(r.lastRun.Unix()), id, r.name)
+		metrics <- prom.MustNewConstMetric(c.benchmarkServerSuccess, prom.GaugeValue, floatOf(r.success), id, r.name)
+	}
+}
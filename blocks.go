@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// blockUsageWindow is the number of most recent days used to project when a
+// block account will run out
+const blockUsageWindow = 7
+
+// BlockAccount is a prepaid block of data purchased for a news server,
+// configured as 'server:size:purchase-date'
+type BlockAccount struct {
+	Server    string
+	Size      int64
+	Purchased time.Time
+}
+
+func (b *BlockAccount) UnmarshalFlag(value string) error {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("block account %q must be in the form server:size:purchase-date", value)
+	}
+	size, err := parseBytes(parts[1])
+	if err != nil {
+		return fmt.Errorf("block account %q: %w", value, err)
+	}
+	purchased, err := time.Parse("2006-01-02", parts[2])
+	if err != nil {
+		return fmt.Errorf("block account %q: invalid purchase date: %w", value, err)
+	}
+
+	b.Server = parts[0]
+	b.Size = size
+	b.Purchased = purchased
+	return nil
+}
+
+// key identifies the block account in the block usage state
+func (b *BlockAccount) key() string {
+	return b.Server + ":" + b.Purchased.Format("2006-01-02")
+}
+
+// blockUsage keeps a running baseline of the bytes used on a block account so
+// that a server volume reset in NZBGet doesn't reset the usage to zero
+type blockUsage struct {
+	Last   int64 `json:"last"`
+	Offset int64 `json:"offset"`
+}
+
+// update records the bytes used according to NZBGet, returning the total
+// used and whether the baseline changed
+func (u *blockUsage) update(used int64) (int64, bool) {
+	changed := used != u.Last
+	if used < u.Last {
+		// NZBGet volume statistics were reset; carry the old total forward
+		u.Offset += u.Last
+	}
+	u.Last = used
+	return u.Offset + used, changed
+}
+
+// LoadBlockUsage reads the block usage baselines saved by a previous run, so
+// that usage carried over NZBGet volume resets survives exporter restarts
+func (c *NZBGetCollector) LoadBlockUsage() error {
+	data, err := os.ReadFile(c.Config.BlockStateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return json.Unmarshal(data, &c.blockUsage)
+}
+
+// saveBlockUsage writes the block usage baselines to the state file. The file
+// is replaced atomically so that a crash can't leave it truncated. c.mu is only
+// held to copy the baselines, so that slow storage doesn't hold up scrapes, and
+// saves are serialised so that an older copy can't overwrite a newer one
+func (c *NZBGetCollector) saveBlockUsage() error {
+	c.blockStateMu.Lock()
+	defer c.blockStateMu.Unlock()
+
+	c.mu.Lock()
+	data, err := json.Marshal(c.blockUsage)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := c.Config.BlockStateFile + ".tmp"
+	err = os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.Config.BlockStateFile)
+}
+
+func (c *NZBGetCollector) collectBlockAccounts(metrics chan<- prom.Metric, config *NZBGetConfig, volume []ServerVolume) {
+	var save bool
+	defer func() {
+		if !save || c.Config.BlockStateFile == "" {
+			return
+		}
+		err := c.saveBlockUsage()
+		if err != nil {
+			log.WithError(err).Error("save block account state")
+		}
+	}()
+
+	// A server may have several blocks, told apart by their purchase date, but
+	// the same block may also be given by both server name and id
+	seen := map[string]bool{}
+	for _, block := range c.Config.BlockAccounts {
+		idx, server := config.FindServer(block.Server)
+		if server == nil {
+			log.WithField("server", block.Server).Warn("block account for unknown news server")
+			continue
+		}
+		id := fmt.Sprintf("%d", idx)
+		purchased := block.Purchased.Format("2006-01-02")
+		if seen[id+":"+purchased] {
+			log.WithField("server", block.Server).
+				WithField("purchased", purchased).
+				Warn("duplicate block account for news server")
+			continue
+		}
+		seen[id+":"+purchased] = true
+		var vol *ServerVolume
+		for i := range volume {
+			if volume[i].ID == idx {
+				vol = &volume[i]
+			}
+		}
+		if vol == nil {
+			continue
+		}
+
+		// Only count usage from the day the block was purchased
+		purchaseDay := int(block.Purchased.Unix() / 86400)
+		var used, recent int64
+		var recentDays int
+		for i, bytes := range vol.BytesPerDay {
+			day := vol.FirstDay + i
+			if day < purchaseDay {
+				continue
+			}
+			used += bytes
+			if i >= len(vol.BytesPerDay)-blockUsageWindow {
+				recent += bytes
+				recentDays++
+			}
+		}
+
+		c.mu.Lock()
+		usage := c.blockUsage[block.key()]
+		if usage == nil {
+			usage = &blockUsage{}
+			c.blockUsage[block.key()] = usage
+		}
+		used, changed := usage.update(used)
+		c.mu.Unlock()
+		save = save || changed
+
+		remaining := block.Size - used
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		name := server.Name
+		metrics <- prom.MustNewConstMetric(c.newsServerBlockSize, prom.GaugeValue, float64(block.Size), id, name, purchased)
+		metrics <- prom.MustNewConstMetric(c.newsServerBlockPurchased, prom.GaugeValue, float64(block.Purchased.Unix()), id, name, purchased)
+		metrics <- prom.MustNewConstMetric(c.newsServerBlockUsed, prom.GaugeValue, float64(used), id, name, purchased)
+		metrics <- prom.MustNewConstMetric(c.newsServerBlockRemaining, prom.GaugeValue, float64(remaining), id, name, purchased)
+
+		if recentDays > 0 && recent > 0 {
+			perDay := float64(recent) / float64(recentDays)
+			days := float64(remaining) / perDay
+			if days > 100*365 {
+				// Too far in the future to be meaningful (or representable)
+				continue
+			}
+			exhaustion := time.Now().Add(time.Duration(days * float64(24*time.Hour)))
+			metrics <- prom.MustNewConstMetric(c.newsServerBlockExhaustion, prom.GaugeValue, float64(exhaustion.Unix()), id, name, purchased)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestBlockUsageUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		usage   []int64
+		total   int64
+		changed bool
+	}{
+		{
+			name:    "first update",
+			usage:   []int64{100},
+			total:   100,
+			changed: true,
+		},
+		{
+			name:    "unchanged",
+			usage:   []int64{100, 100},
+			total:   100,
+			changed: false,
+		},
+		{
+			name:    "growing",
+			usage:   []int64{100, 250},
+			total:   250,
+			changed: true,
+		},
+		{
+			name:    "reset carries total over",
+			usage:   []int64{100, 250, 30},
+			total:   280,
+			changed: true,
+		},
+		{
+			name:    "reset to zero",
+			usage:   []int64{100, 0},
+			total:   100,
+			changed: true,
+		},
+		{
+			name:    "repeated resets",
+			usage:   []int64{100, 20, 50, 10},
+			total:   160,
+			changed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var usage blockUsage
+			var total int64
+			var changed bool
+			for _, used := range test.usage {
+				total, changed = usage.update(used)
+			}
+			if total != test.total {
+				t.Errorf("got total %d, want %d", total, test.total)
+			}
+			if changed != test.changed {
+				t.Errorf("got changed %v, want %v", changed, test.changed)
+			}
+		})
+	}
+}
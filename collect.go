@@ -1,47 +1,128 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	prom "github.com/prometheus/client_golang/prometheus"
 )
 
+// postAgeBuckets are the history post age histogram buckets, from a day up to
+// roughly the longest retention offered by news servers
+var postAgeBuckets = []float64{
+	day, 7 * day, 30 * day, 90 * day, 180 * day,
+	365 * day, 2 * 365 * day, 3 * 365 * day, 5 * 365 * day,
+	10 * 365 * day, 15 * 365 * day,
+}
+
+// healthBuckets are the article health histogram buckets, as a ratio. NZBGet
+// defaults to deleting downloads that drop below 0.85 to 0.95 health
+var healthBuckets = []float64{
+	0.5, 0.75, 0.85, 0.9, 0.95, 0.98, 0.99, 0.995, 0.999, 1,
+}
+
 type NZBGetCollector struct {
 	Config *ExporterConfig
 
-	version *prom.Desc
-
-	articleCache    *prom.Desc
-	diskSpaceFree   *prom.Desc
-	diskSpaceMin    *prom.Desc
-	downloadLimit   *prom.Desc
-	downloadPaused  *prom.Desc
-	downloadTimeSec *prom.Desc
-	downloadedSize  *prom.Desc
-	forcedSize      *prom.Desc
-	postJobCount    *prom.Desc
-	postPaused      *prom.Desc
-	quotaDay        *prom.Desc
-	quotaMonth      *prom.Desc
-	quotaReached    *prom.Desc
-	remainingSize   *prom.Desc
-	resumeTime      *prom.Desc
-	scanPaused      *prom.Desc
-	serverStandBy   *prom.Desc
-	startTime       *prom.Desc
-	threadCount     *prom.Desc
-	urlCount        *prom.Desc
+	// mu guards state carried between scrapes
+	mu               sync.Mutex
+	serverVersion    ServerVersion
+	blockUsage       map[string]*blockUsage
+	newsServerStates map[int]*newsServerState
+	diskBenchmark    *benchmarkResult
+	serverBenchmarks map[int]*benchmarkResult
+	unknownEnumsSeen map[UnknownEnum]bool
+	historyTracker   historyTracker
+	pendingFailures  []pendingFailure
+	urlQueueSeen     map[int]time.Time
+
+	// blockStateMu orders writes of the block usage state file
+	blockStateMu sync.Mutex
+
+	unknownEnums             *prom.CounterVec
+	decodeRepairs            *prom.CounterVec
+	historyScriptStatusTotal *prom.CounterVec
+	jobFailuresTotal         *prom.CounterVec
+
+	version    *prom.Desc
+	apiFeature *prom.Desc
+
+	articleCache        *prom.Desc
+	averageDownloadRate *prom.Desc
+	diskSpaceFree       *prom.Desc
+	diskSpaceMin        *prom.Desc
+	diskSpaceTotal      *prom.Desc
+	downloadLimit       *prom.Desc
+	downloadPaused      *prom.Desc
+	downloadRate        *prom.Desc
+	downloadTimeSec     *prom.Desc
+	downloadedSize      *prom.Desc
+	forcedSize          *prom.Desc
+	interDiskSpaceFree  *prom.Desc
+	interDiskSpaceTotal *prom.Desc
+	postJobCount        *prom.Desc
+	postPaused          *prom.Desc
+	quotaDay            *prom.Desc
+	quotaMonth          *prom.Desc
+	quotaReached        *prom.Desc
+	remainingSize       *prom.Desc
+	resumeTime          *prom.Desc
+	scanPaused          *prom.Desc
+	serverStandBy       *prom.Desc
+	startTime           *prom.Desc
+	threadCount         *prom.Desc
+	urlCount            *prom.Desc
+
+	sysInfo              *prom.Desc
+	sysInfoNetwork       *prom.Desc
+	sysInfoTool          *prom.Desc
+	sysInfoToolAvailable *prom.Desc
+	sysInfoLibrary       *prom.Desc
+
+	systemHealthy      *prom.Desc
+	systemHealthAlerts *prom.Desc
+
+	extensionInfo    *prom.Desc
+	extensionEnabled *prom.Desc
 
 	newsServerActive         *prom.Desc
 	newsServerBytes          *prom.Desc
 	newsServerArticleSuccess *prom.Desc
 	newsServerArticleFailed  *prom.Desc
 
+	newsServerStateChanges   *prom.Desc
+	newsServerInactiveTime   *prom.Desc
+	newsServerLastTransition *prom.Desc
+
+	newsServerRetention         *prom.Desc
+	newsServerRetentionExceeded *prom.Desc
+	newsServerRetentionSuccess  *prom.Desc
+	newsServerRetentionFailed   *prom.Desc
+	newsServerRetentionFailure  *prom.Desc
+
+	newsServerBlockSize       *prom.Desc
+	newsServerBlockPurchased  *prom.Desc
+	newsServerBlockUsed       *prom.Desc
+	newsServerBlockRemaining  *prom.Desc
+	newsServerBlockExhaustion *prom.Desc
+
+	benchmarkDiskThroughput   *prom.Desc
+	benchmarkDiskDuration     *prom.Desc
+	benchmarkDiskLastRun      *prom.Desc
+	benchmarkDiskSuccess      *prom.Desc
+	benchmarkServerThroughput *prom.Desc
+	benchmarkServerDuration   *prom.Desc
+	benchmarkServerLastRun    *prom.Desc
+	benchmarkServerSuccess    *prom.Desc
+
 	historyCategoryCount       *prom.Desc
 	historyFileSizeBytes       *prom.Desc
 	historyFileCount           *prom.Desc
@@ -58,25 +139,111 @@ type NZBGetCollector struct {
 	historyStatusCount         *prom.Desc
 	historyParStatusCount      *prom.Desc
 	historyUnpackStatusCount   *prom.Desc
+	historyPostAge             *prom.Desc
+	historyScriptStatusCount   *prom.Desc
+	historyDeleteStatusCount   *prom.Desc
+	historyMarkStatusCount     *prom.Desc
+	historyMoveStatusCount     *prom.Desc
+	historyURLStatusCount      *prom.Desc
+	historyRetryDataCount      *prom.Desc
+	historyDeletedCount        *prom.Desc
+	historyDupeModeCount       *prom.Desc
+	historyDupeDeletedCount    *prom.Desc
+	historyDupeHiddenCount     *prom.Desc
+	historyDupeKeyRetried      *prom.Desc
+	historyDupeWastedBytes     *prom.Desc
+
+	historyURLFetchCount *prom.Desc
+	queueURLCount        *prom.Desc
+	queueURLOldestAge    *prom.Desc
+
+	historyIndexerCount              *prom.Desc
+	historyIndexerHealthDeleted      *prom.Desc
+	historyIndexerFailureRatio       *prom.Desc
+	historyIndexerHealthDeletedRatio *prom.Desc
+	historyIndexerHealth             *prom.Desc
+	historyIndexerSize               *prom.Desc
+
+	historyParamLabelCount *prom.Desc
+	historyParamLabelSize  *prom.Desc
+	historyParamLabelTime  *prom.Desc
+
+	historyRecentFailure *prom.Desc
+
+	historyWindowCount   *prom.Desc
+	historyWindowSize    *prom.Desc
+	historyWindowSuccess *prom.Desc
+
+	historyHealth             *prom.Desc
+	historyHealthNearCritical *prom.Desc
+	queueHealth               *prom.Desc
+	queueHealthNearCritical   *prom.Desc
+	queueHealthHeadroom       *prom.Desc
+
+	historyParPhaseTime       *prom.Desc
+	historyParExtraBlocks     *prom.Desc
+	historyParHealthCritical  *prom.Desc
+	historyParCheckedCount    *prom.Desc
+	historyParRepairCount     *prom.Desc
+	historyParRepairRatio     *prom.Desc
+	newsServerParCheckedCount *prom.Desc
+	newsServerParRepairCount  *prom.Desc
+	newsServerParRepairRatio  *prom.Desc
 }
 
 func NewNZBGetCollector(config *ExporterConfig) *NZBGetCollector {
 	ns := config.Namespace
 
 	return &NZBGetCollector{
-		Config: config,
+		Config:           config,
+		blockUsage:       map[string]*blockUsage{},
+		newsServerStates: map[int]*newsServerState{},
+		serverBenchmarks: map[int]*benchmarkResult{},
+		unknownEnumsSeen: map[UnknownEnum]bool{},
+		urlQueueSeen:     map[int]time.Time{},
+
+		unknownEnums: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: ns,
+			Name:      "unknown_enum_values_total",
+			Help:      "Number of distinct unrecognised enum values decoded from the NZBGet api, per enum type",
+		}, []string{"type"}),
+		decodeRepairs: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: ns,
+			Name:      "decode_repairs_total",
+			Help:      "Number of out of range integers repaired when decoding NZBGet api responses",
+		}, []string{"endpoint", "field"}),
+		historyScriptStatusTotal: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: ns,
+			Name:      "history_script_status_total",
+			Help:      "Number of post-processing script results per script and status, counted as jobs complete",
+		}, config.historyLabels("script", "status")),
+		jobFailuresTotal: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: ns,
+			Name:      "job_failures_total",
+			Help:      "Number of failed jobs per category and reason, classified from the job log as jobs complete. unclassified when too many failed at once to fetch every log",
+		}, []string{"category", "reason"}),
 
 		version: prom.NewDesc(
 			prom.BuildFQName(ns, "", "version"),
 			"always 1. label 'version' contains nzbget server version",
 			[]string{"version"}, nil,
 		),
+		apiFeature: prom.NewDesc(
+			prom.BuildFQName(ns, "api", "feature"),
+			"1 if the nzbget server version supports the api feature, 0 otherwise",
+			[]string{"feature"}, nil,
+		),
 
 		articleCache: prom.NewDesc(
 			prom.BuildFQName(ns, "article_cache", "bytes"),
 			"Current usage of article cache",
 			nil, nil,
 		),
+		averageDownloadRate: prom.NewDesc(
+			prom.BuildFQName(ns, "download", "average_rate_bytes"),
+			"Average download rate since server start, in bytes per second",
+			nil, nil,
+		),
 		diskSpaceFree: prom.NewDesc(
 			prom.BuildFQName(ns, "disk", "free_bytes"),
 			"Free disk space on 'DestDir'",
@@ -87,6 +254,26 @@ func NewNZBGetCollector(config *ExporterConfig) *NZBGetCollector {
 			"Disk space limit before pausing the download queue",
 			nil, nil,
 		),
+		diskSpaceTotal: prom.NewDesc(
+			prom.BuildFQName(ns, "disk", "total_bytes"),
+			"Total disk space on 'DestDir' (nzbget-ng only)",
+			nil, nil,
+		),
+		downloadRate: prom.NewDesc(
+			prom.BuildFQName(ns, "download", "rate_bytes"),
+			"Current download rate, in bytes per second",
+			nil, nil,
+		),
+		interDiskSpaceFree: prom.NewDesc(
+			prom.BuildFQName(ns, "inter_disk", "free_bytes"),
+			"Free disk space on 'InterDir' (nzbget-ng only)",
+			nil, nil,
+		),
+		interDiskSpaceTotal: prom.NewDesc(
+			prom.BuildFQName(ns, "inter_disk", "total_bytes"),
+			"Total disk space on 'InterDir' (nzbget-ng only)",
+			nil, nil,
+		),
 		downloadLimit: prom.NewDesc(
 			prom.BuildFQName(ns, "download", "limit"),
 			"Current download limit, in bytes per second",
@@ -170,6 +357,54 @@ func NewNZBGetCollector(config *ExporterConfig) *NZBGetCollector {
 			nil, nil,
 		),
 
+		sysInfo: prom.NewDesc(
+			prom.BuildFQName(ns, "sysinfo", "info"),
+			"always 1. labels describe the operating system and cpu of the nzbget host",
+			[]string{"os", "os_version", "cpu", "arch"}, nil,
+		),
+		sysInfoNetwork: prom.NewDesc(
+			prom.BuildFQName(ns, "sysinfo", "network_info"),
+			"always 1. labels contain the private and public ip addresses of the nzbget host",
+			[]string{"private_ip", "public_ip"}, nil,
+		),
+		sysInfoTool: prom.NewDesc(
+			prom.BuildFQName(ns, "sysinfo", "tool_info"),
+			"always 1. labels contain the version of each tool bundled with or used by nzbget, such as unrar and 7-Zip",
+			[]string{"tool", "version"}, nil,
+		),
+		sysInfoToolAvailable: prom.NewDesc(
+			prom.BuildFQName(ns, "sysinfo", "tool_available"),
+			"1 if the tool was found on the nzbget host, 0 otherwise",
+			[]string{"tool"}, nil,
+		),
+		sysInfoLibrary: prom.NewDesc(
+			prom.BuildFQName(ns, "sysinfo", "library_info"),
+			"always 1. labels contain the version of each library nzbget was built with",
+			[]string{"library", "version"}, nil,
+		),
+
+		systemHealthy: prom.NewDesc(
+			prom.BuildFQName(ns, "system_health", "healthy"),
+			"1 if nzbget-ng reports no system health errors, 0 otherwise",
+			nil, nil,
+		),
+		systemHealthAlerts: prom.NewDesc(
+			prom.BuildFQName(ns, "system_health", "alerts"),
+			"Number of nzbget-ng system health alerts per config section",
+			[]string{"section", "severity"}, nil,
+		),
+
+		extensionInfo: prom.NewDesc(
+			prom.BuildFQName(ns, "extension", "info"),
+			"always 1. one per installed extension (script) and kind of extension",
+			[]string{"name", "version", "kind"}, nil,
+		),
+		extensionEnabled: prom.NewDesc(
+			prom.BuildFQName(ns, "extension", "enabled"),
+			"always 1. one per extension enabled globally, or for a category",
+			[]string{"name", "scope", "category"}, nil,
+		),
+
 		newsServerActive: prom.NewDesc(
 			prom.BuildFQName(ns, "news_server", "active"),
 			"News server used for obtaining articles, 1 if active",
@@ -190,86 +425,409 @@ func NewNZBGetCollector(config *ExporterConfig) *NZBGetCollector {
 			"Total failed articles from this news server",
 			[]string{"id", "server"}, nil,
 		),
+		newsServerStateChanges: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server", "state_changes_total"),
+			"Number of times this news server changed between active and inactive",
+			[]string{"id", "server"}, nil,
+		),
+		newsServerInactiveTime: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server", "inactive_seconds_total"),
+			"Total time this news server has been observed inactive, in seconds",
+			[]string{"id", "server"}, nil,
+		),
+		newsServerLastTransition: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server", "last_transition_time_seconds"),
+			"Time this news server last changed between active and inactive, in unixtime",
+			[]string{"id", "server"}, nil,
+		),
+
+		newsServerRetention: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server", "retention_days"),
+			"Configured retention of this news server in days, 0 for unlimited",
+			[]string{"id", "server"}, nil,
+		),
+		newsServerRetentionExceeded: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_retention", "exceeded_count"),
+			"Number of history items for which this news server was asked for articles older than its configured retention",
+			config.historyLabels("id", "server"), nil,
+		),
+		newsServerRetentionSuccess: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_retention", "article_success_count"),
+			"Number of successful articles in history from this news server, inside or outside its configured retention",
+			config.historyLabels("id", "server", "retention"), nil,
+		),
+		newsServerRetentionFailed: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_retention", "article_failed_count"),
+			"Number of failed articles in history from this news server, inside or outside its configured retention",
+			config.historyLabels("id", "server", "retention"), nil,
+		),
+		newsServerRetentionFailure: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_retention", "failure_ratio"),
+			"Ratio of failed articles in history from this news server, inside or outside its configured retention",
+			config.historyLabels("id", "server", "retention"), nil,
+		),
+
+		newsServerBlockSize: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_block", "size_bytes"),
+			"Purchased size of the block account for this news server",
+			[]string{"id", "server", "purchase_date"}, nil,
+		),
+		newsServerBlockPurchased: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_block", "purchase_time_seconds"),
+			"Purchase date of the block account for this news server, in unixtime",
+			[]string{"id", "server", "purchase_date"}, nil,
+		),
+		newsServerBlockUsed: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_block", "used_bytes"),
+			"Bytes downloaded from this news server since the block was purchased",
+			[]string{"id", "server", "purchase_date"}, nil,
+		),
+		newsServerBlockRemaining: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_block", "remaining_bytes"),
+			"Bytes remaining on the block account for this news server",
+			[]string{"id", "server", "purchase_date"}, nil,
+		),
+		newsServerBlockExhaustion: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_block", "exhaustion_time_seconds"),
+			"Projected time the block account runs out based on recent usage, in unixtime",
+			[]string{"id", "server", "purchase_date"}, nil,
+		),
+
+		benchmarkDiskThroughput: prom.NewDesc(
+			prom.BuildFQName(ns, "benchmark_disk", "throughput_bytes"),
+			"Write throughput of the last disk speed benchmark, in bytes per second",
+			[]string{"dir"}, nil,
+		),
+		benchmarkDiskDuration: prom.NewDesc(
+			prom.BuildFQName(ns, "benchmark_disk", "duration_seconds"),
+			"Duration of the last disk speed benchmark, in seconds",
+			[]string{"dir"}, nil,
+		),
+		benchmarkDiskLastRun: prom.NewDesc(
+			prom.BuildFQName(ns, "benchmark_disk", "last_run_time_seconds"),
+			"Time the last disk speed benchmark was run, in unixtime",
+			[]string{"dir"}, nil,
+		),
+		benchmarkDiskSuccess: prom.NewDesc(
+			prom.BuildFQName(ns, "benchmark_disk", "success"),
+			"1 if the last disk speed benchmark succeeded, 0 otherwise",
+			[]string{"dir"}, nil,
+		),
+		benchmarkServerThroughput: prom.NewDesc(
+			prom.BuildFQName(ns, "benchmark_news_server", "throughput_bytes"),
+			"Download throughput of the last speed benchmark of this news server, in bytes per second",
+			[]string{"id", "server"}, nil,
+		),
+		benchmarkServerDuration: prom.NewDesc(
+			prom.BuildFQName(ns, "benchmark_news_server", "duration_seconds"),
+			"Duration of the last speed benchmark of this news server, in seconds",
+			[]string{"id", "server"}, nil,
+		),
+		benchmarkServerLastRun: prom.NewDesc(
+			prom.BuildFQName(ns, "benchmark_news_server", "last_run_time_seconds"),
+			"Time the last speed benchmark of this news server was run, in unixtime",
+			[]string{"id", "server"}, nil,
+		),
+		benchmarkServerSuccess: prom.NewDesc(
+			prom.BuildFQName(ns, "benchmark_news_server", "success"),
+			"1 if the last speed benchmark of this news server succeeded, 0 otherwise",
+			[]string{"id", "server"}, nil,
+		),
 
 		historyCategoryCount: prom.NewDesc(
 			prom.BuildFQName(ns, "history_category", "count"),
 			"Number of history items in each category",
-			[]string{"category"}, nil,
+			config.historyLabels("category"), nil,
 		),
 		historyFileSizeBytes: prom.NewDesc(
 			prom.BuildFQName(ns, "history_file_size", "total_bytes"),
 			"Total bytes of all files in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyFileCount: prom.NewDesc(
 			prom.BuildFQName(ns, "history_file", "count"),
 			"Number of files in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyRemainingFileCount: prom.NewDesc(
 			prom.BuildFQName(ns, "history_file", "remaining_count"),
 			"Number of remaining files parked in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyArticleCount: prom.NewDesc(
 			prom.BuildFQName(ns, "history_article", "count"),
 			"Number of articles in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historySuccessArticleCount: prom.NewDesc(
 			prom.BuildFQName(ns, "history_article", "success_count"),
 			"Number of successful articles in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyFailedArticleCount: prom.NewDesc(
 			prom.BuildFQName(ns, "history_article", "failed_count"),
 			"Number of failed articles in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyDownloadTime: prom.NewDesc(
 			prom.BuildFQName(ns, "history_download", "time_seconds"),
 			"Download time in seconds",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyDownloadSizeBytes: prom.NewDesc(
 			prom.BuildFQName(ns, "history_download", "size_bytes"),
 			"Total downloaded size in history, in bytes",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyPostTime: prom.NewDesc(
 			prom.BuildFQName(ns, "history_post_process", "time_seconds"),
 			"Total post-processing time in seconds in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyParTime: prom.NewDesc(
 			prom.BuildFQName(ns, "history_par", "time_seconds"),
 			"Total par-check time in seconds in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyRepairTime: prom.NewDesc(
 			prom.BuildFQName(ns, "history_par_repair", "time_seconds"),
 			"Par-repair time in seconds in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyUnpackTime: prom.NewDesc(
 			prom.BuildFQName(ns, "history_unpack", "time_seconds"),
 			"Unpack time in seconds in history",
-			nil, nil,
+			config.historyLabels(), nil,
 		),
 		historyStatusCount: prom.NewDesc(
 			prom.BuildFQName(ns, "history_status", "count"),
 			"Number of history items per status",
-			[]string{"reason", "status"}, nil,
+			config.historyLabels("reason", "status"), nil,
 		),
 		historyParStatusCount: prom.NewDesc(
 			prom.BuildFQName(ns, "history_par_status", "count"),
 			"Number of history items per par status",
-			[]string{"status"}, nil,
+			config.historyLabels("status"), nil,
 		),
 		historyUnpackStatusCount: prom.NewDesc(
 			prom.BuildFQName(ns, "history_unpack_status", "count"),
 			"Number of history items per unpack status",
-			[]string{"status"}, nil,
+			config.historyLabels("status"), nil,
+		),
+		historyScriptStatusCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_script_status", "count"),
+			"Number of history items per post-processing script and script status",
+			config.historyLabels("script", "status"), nil,
+		),
+		historyDeleteStatusCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_delete_status", "count"),
+			"Number of history items per category and delete status",
+			config.historyLabels("status", "category"), nil,
+		),
+		historyMarkStatusCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_mark_status", "count"),
+			"Number of history items per category and mark status",
+			config.historyLabels("status", "category"), nil,
+		),
+		historyMoveStatusCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_move_status", "count"),
+			"Number of history items per category and move status",
+			config.historyLabels("status", "category"), nil,
+		),
+		historyURLStatusCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_url_status", "count"),
+			"Number of history items per category and url fetch status",
+			config.historyLabels("status", "category"), nil,
+		),
+		historyRetryDataCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_retry_data", "count"),
+			"Number of history items per category with downloaded data kept for a retry",
+			config.historyLabels("category"), nil,
+		),
+		historyDeletedCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_deleted", "count"),
+			"Number of history items per category that were deleted",
+			config.historyLabels("category"), nil,
+		),
+		historyDupeModeCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_dupe", "mode_count"),
+			"Number of history items sharing a dupe key with another item, per dupe mode",
+			config.historyLabels("mode"), nil,
+		),
+		historyDupeDeletedCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_dupe", "deleted_count"),
+			"Number of history items per category deleted as duplicates",
+			config.historyLabels("category"), nil,
+		),
+		historyDupeHiddenCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_dupe", "hidden_count"),
+			"Number of hidden duplicate (DUP) history records. Always 0 unless --history-hidden is set, as NZBGet only returns hidden records on request",
+			nil, nil,
+		),
+		historyDupeKeyRetried: prom.NewDesc(
+			prom.BuildFQName(ns, "history_dupe", "retried_keys"),
+			"Number of distinct dupe keys with more than one history item",
+			nil, nil,
+		),
+		historyDupeWastedBytes: prom.NewDesc(
+			prom.BuildFQName(ns, "history_dupe", "deleted_bytes"),
+			"Total downloaded size of history items deleted as duplicates, in bytes",
+			config.historyLabels(), nil,
+		),
+		historyURLFetchCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_url_fetch", "count"),
+			"Number of history items fetched from a url, per fetch status, category and indexer host",
+			config.historyLabels("status", "category", "host"), nil,
+		),
+		queueURLCount: prom.NewDesc(
+			prom.BuildFQName(ns, "queue_url", "count"),
+			"Number of urls in the queue waiting to be fetched, per indexer host and status",
+			[]string{"host", "status"}, nil,
+		),
+		queueURLOldestAge: prom.NewDesc(
+			prom.BuildFQName(ns, "queue_url_oldest_age", "seconds"),
+			"Time since the oldest url in the queue was first seen by the exporter, in seconds. 0 when the url queue is empty",
+			nil, nil,
+		),
+		historyIndexerCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_indexer", "count"),
+			"Number of history items per indexer and status",
+			config.historyLabels("status", "indexer"), nil,
+		),
+		historyIndexerHealthDeleted: prom.NewDesc(
+			prom.BuildFQName(ns, "history_indexer", "health_deleted_count"),
+			"Number of history items per indexer deleted for health",
+			config.historyLabels("indexer"), nil,
+		),
+		historyIndexerFailureRatio: prom.NewDesc(
+			prom.BuildFQName(ns, "history_indexer", "failure_ratio"),
+			"Share of history items per indexer that failed",
+			config.historyLabels("indexer"), nil,
+		),
+		historyIndexerHealthDeletedRatio: prom.NewDesc(
+			prom.BuildFQName(ns, "history_indexer", "health_deleted_ratio"),
+			"Share of history items per indexer deleted for health",
+			config.historyLabels("indexer"), nil,
+		),
+		historyIndexerHealth: prom.NewDesc(
+			prom.BuildFQName(ns, "history_indexer", "health_average_ratio"),
+			"Average article health of history items per indexer, as a ratio",
+			config.historyLabels("indexer"), nil,
+		),
+		historyIndexerSize: prom.NewDesc(
+			prom.BuildFQName(ns, "history_indexer", "downloaded_bytes"),
+			"Total downloaded size of history items per indexer, in bytes",
+			config.historyLabels("indexer"), nil,
+		),
+		historyParamLabelCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_param_label", "count"),
+			fmt.Sprintf("Number of history items per status and category, by the %s label from nzb parameters", config.ParamLabelName),
+			config.historyLabels("status", "category", string(config.ParamLabelName)), nil,
+		),
+		historyParamLabelSize: prom.NewDesc(
+			prom.BuildFQName(ns, "history_param_label", "downloaded_bytes"),
+			fmt.Sprintf("Total downloaded size of history items in bytes, by the %s label from nzb parameters", config.ParamLabelName),
+			config.historyLabels(string(config.ParamLabelName)), nil,
+		),
+		historyParamLabelTime: prom.NewDesc(
+			prom.BuildFQName(ns, "history_param_label", "download_time_seconds"),
+			fmt.Sprintf("Total download time of history items in seconds, by the %s label from nzb parameters", config.ParamLabelName),
+			config.historyLabels(string(config.ParamLabelName)), nil,
+		),
+		historyRecentFailure: prom.NewDesc(
+			prom.BuildFQName(ns, "history_recent_failure", "info"),
+			"always 1. the most recent failed or deleted history items",
+			[]string{"nzbid", "name", "category", "status"}, nil,
+		),
+		historyWindowCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_window", "count"),
+			"Number of history items per category completed, completed with a warning, failed or deleted within each window",
+			config.historyLabels("status", "category", "window"), nil,
+		),
+		historyWindowSize: prom.NewDesc(
+			prom.BuildFQName(ns, "history_window", "downloaded_bytes"),
+			"Total downloaded size of history items per category within each window, in bytes",
+			config.historyLabels("category", "window"), nil,
+		),
+		historyWindowSuccess: prom.NewDesc(
+			prom.BuildFQName(ns, "history_window", "success_ratio"),
+			"Share of history items per category within each window that completed successfully, without a warning",
+			config.historyLabels("category", "window"), nil,
+		),
+		historyHealth: prom.NewDesc(
+			prom.BuildFQName(ns, "history_health", "ratio"),
+			"Article health of history items at completion, as a ratio",
+			config.historyLabels("category"), nil,
+		),
+		historyHealthNearCritical: prom.NewDesc(
+			prom.BuildFQName(ns, "history_health", "near_critical_count"),
+			"Number of history items that completed within the health margin of their critical health",
+			config.historyLabels("category"), nil,
+		),
+		queueHealth: prom.NewDesc(
+			prom.BuildFQName(ns, "queue_health", "ratio"),
+			"Current article health of items in the download queue, as a ratio",
+			[]string{"category"}, nil,
+		),
+		queueHealthNearCritical: prom.NewDesc(
+			prom.BuildFQName(ns, "queue_health", "near_critical_count"),
+			"Number of items in the download queue within the health margin of their critical health",
+			[]string{"category"}, nil,
+		),
+		queueHealthHeadroom: prom.NewDesc(
+			prom.BuildFQName(ns, "queue_health", "headroom_ratio"),
+			"Health above critical health of the queue items within the health margin nearest their critical health, as a ratio. Items are deleted or paused for health below 0",
+			[]string{"nzbid", "name", "category", "status"}, nil,
+		),
+		historyParPhaseTime: prom.NewDesc(
+			prom.BuildFQName(ns, "history_par", "phase_time_seconds"),
+			"Par-check time in seconds per category, split into verify and repair phases",
+			config.historyLabels("phase", "category"), nil,
+		),
+		historyParExtraBlocks: prom.NewDesc(
+			prom.BuildFQName(ns, "history_par", "extra_blocks"),
+			"Number of extra par blocks received from, or donated to, duplicates per category",
+			config.historyLabels("direction", "category"), nil,
+		),
+		historyParHealthCritical: prom.NewDesc(
+			prom.BuildFQName(ns, "history_par", "health_critical_ratio"),
+			"Ratio of article health to critical health of history items at completion. Below 1 cannot be repaired",
+			config.historyLabels("category"), nil,
+		),
+		historyParCheckedCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_par", "checked_count"),
+			"Number of history items per category that were par-checked",
+			config.historyLabels("category"), nil,
+		),
+		historyParRepairCount: prom.NewDesc(
+			prom.BuildFQName(ns, "history_par", "repair_needed_count"),
+			"Number of par-checked history items per category that needed repair",
+			config.historyLabels("category"), nil,
+		),
+		historyParRepairRatio: prom.NewDesc(
+			prom.BuildFQName(ns, "history_par", "repair_needed_ratio"),
+			"Share of par-checked history items per category that needed repair",
+			config.historyLabels("category"), nil,
+		),
+		newsServerParCheckedCount: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_par", "checked_count"),
+			"Number of par-checked history items this news server served articles for",
+			config.historyLabels("id", "server"), nil,
+		),
+		newsServerParRepairCount: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_par", "repair_needed_count"),
+			"Number of par-checked history items this news server served articles for that needed repair",
+			config.historyLabels("id", "server"), nil,
+		),
+		newsServerParRepairRatio: prom.NewDesc(
+			prom.BuildFQName(ns, "news_server_par", "repair_needed_ratio"),
+			"Share of par-checked history items this news server served articles for that needed repair",
+			config.historyLabels("id", "server"), nil,
+		),
+		historyPostAge: prom.NewDesc(
+			prom.BuildFQName(ns, "history_post_age", "seconds"),
+			"Age of the oldest article in each history item when it reached history, in seconds",
+			config.historyLabels("category", "status"), nil,
 		),
 	}
 }
@@ -277,12 +835,25 @@ func NewNZBGetCollector(config *ExporterConfig) *NZBGetCollector {
 func (c *NZBGetCollector) Collect(metrics chan<- prom.Metric) {
 	var config NZBGetConfig
 	var status Status
-	var version string
 	var volume []ServerVolume
 	var history []History
+	var groups []Group
+
+	// The version decides which api methods are called and which metrics are
+	// exported, so it must be known before anything else is fetched. Otherwise
+	// the first scrape, or the first after NZBGet is upgraded, would use the
+	// features of the previous version
+	version, err := c.fetchVersion()
+	if err != nil {
+		log.WithError(err).Error("api get version")
+		metrics <- prom.NewInvalidMetric(prom.NewInvalidDesc(err), err)
+	} else {
+		metrics <- prom.MustNewConstMetric(c.version, prom.GaugeValue, 1, version)
+		c.collectFeatures(metrics)
+	}
 
 	var wg sync.WaitGroup
-	wg.Add(4)
+	wg.Add(3)
 
 	// Wait for config separately as multiple gothreads require it
 	var cfgWg sync.WaitGroup
@@ -304,18 +875,8 @@ func (c *NZBGetCollector) Collect(metrics chan<- prom.Metric) {
 	go func() {
 		defer wg.Done()
 
-		err := c.getApi("version", &version)
-		if err != nil {
-			log.WithError(err).Error("api get version")
-			metrics <- prom.NewInvalidMetric(prom.NewInvalidDesc(err), err)
-			return
-		}
-		metrics <- prom.MustNewConstMetric(c.version, prom.GaugeValue, 1, version)
-	}()
-
-	go func() {
-		defer wg.Done()
-
+		// Taken before the request, see newsServerState.observe
+		requested := time.Now()
 		err := c.getApi("status", &status)
 		if err != nil {
 			log.WithError(err).Error("api get status")
@@ -341,11 +902,21 @@ func (c *NZBGetCollector) Collect(metrics chan<- prom.Metric) {
 		metrics <- prom.MustNewConstMetric(c.startTime, prom.GaugeValue, float64(status.StartTime.Unix()))
 		metrics <- prom.MustNewConstMetric(c.threadCount, prom.GaugeValue, float64(status.ThreadCount))
 		metrics <- prom.MustNewConstMetric(c.urlCount, prom.GaugeValue, float64(status.URLCount))
+		metrics <- prom.MustNewConstMetric(c.downloadRate, prom.GaugeValue, float64(status.DownloadRate))
+		metrics <- prom.MustNewConstMetric(c.averageDownloadRate, prom.GaugeValue, float64(status.AverageDownloadRate))
+		if c.HasFeature(FeatureDiskTotals) {
+			metrics <- prom.MustNewConstMetric(c.diskSpaceTotal, prom.GaugeValue, float64(status.TotalDiskSpace))
+			metrics <- prom.MustNewConstMetric(c.interDiskSpaceFree, prom.GaugeValue, float64(status.FreeInterDiskSpace))
+			metrics <- prom.MustNewConstMetric(c.interDiskSpaceTotal, prom.GaugeValue, float64(status.TotalInterDiskSpace))
+		}
+
+		c.observeNewsServers(requested, status.NewsServers)
 
 		cfgWg.Wait()
 		if cfgErr {
 			return
 		}
+		c.collectNewsServerStates(metrics, &config)
 		for _, srv := range status.NewsServers {
 			idx := srv.ID
 			id := fmt.Sprintf("%d", srv.ID)
@@ -356,6 +927,47 @@ func (c *NZBGetCollector) Collect(metrics chan<- prom.Metric) {
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		err := c.getApi("listgroups", &groups)
+		if err != nil {
+			log.WithError(err).Error("api get listgroups")
+			metrics <- prom.NewInvalidMetric(prom.NewInvalidDesc(err), err)
+			return
+		}
+		c.collectQueue(metrics, groups)
+		c.collectURLQueue(metrics, groups)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		cfgWg.Wait()
+		if cfgErr {
+			return
+		}
+		c.collectExtensions(metrics, &config)
+	}()
+
+	if c.HasFeature(FeatureSystemHealth) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.collectSystemHealth(metrics)
+		}()
+	}
+
+	if c.Config.CollectSysInfo && c.HasFeature(FeatureSysInfo) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.collectSysInfo(metrics)
+		}()
+	}
+
 	go func() {
 		defer wg.Done()
 
@@ -386,86 +998,254 @@ func (c *NZBGetCollector) Collect(metrics chan<- prom.Metric) {
 			metrics <- prom.MustNewConstMetric(c.newsServerArticleFailed, prom.CounterValue, float64(volume[idx].TotalArticleFailed), id, name)
 
 		}
+
+		c.collectBlockAccounts(metrics, &config, volume)
 	}()
 
 	go func() {
 		defer wg.Done()
 
-		err := c.getApi("history", &history)
+		var err error
+		if c.Config.HistoryHidden {
+			err = c.callApi("history", []interface{}{true}, &history)
+		} else {
+			err = c.getApi("history", &history)
+		}
 		if err != nil {
 			log.WithError(err).Error("api get history")
 			metrics <- prom.NewInvalidMetric(prom.NewInvalidDesc(err), err)
 			return
 		}
 
-		var (
-			fileSize int64
-			fileCount,
-			remainingCount,
-			articleCount,
-			articleSuccessCount,
-			articleFailureCount,
-			downloadTime uint64
-			downloadSize int64
-			postTime,
-			parTime,
-			repairTime,
-			unpackTime uint64
-
-			categories   = map[string]uint64{}
-			statuses     = map[string]map[string]uint64{}
-			parStatus    = map[string]uint64{}
-			unpackStatus = map[string]uint64{}
-		)
+		c.countUnknownEnums(history)
+
+		partitions := c.partitionHistory(history)
+		for hidden, items := range partitions {
+			c.collectHistory(metrics, items, hidden)
+			c.collectURLHistory(metrics, items, hidden)
+			c.collectIndexers(metrics, items, hidden)
+			c.collectParamLabels(metrics, items, hidden)
+			c.collectHistoryWindows(metrics, items, hidden)
+		}
+		c.collectDupes(metrics, history)
+		c.collectRecentFailures(metrics, history)
 
-		for _, hi := range history {
-			fileSize += hi.FileSize
-			fileCount += hi.FileCount
-			remainingCount += hi.RemainingFileCount
-			articleCount += hi.TotalArticles
-			articleSuccessCount += hi.SuccessArticles
-			articleFailureCount += hi.FailedArticles
-			downloadTime += hi.DownloadTimeSec
-			downloadSize += hi.DownloadedSize
-			postTime += hi.PostTotalTimeSec
-			parTime += hi.ParTimeSec
-			repairTime += hi.ParTimeSec
-			unpackTime += hi.UnpackTimeSec
-
-			categories[hi.Category]++
-			parStatus[strings.ToLower(hi.ParStatus.String())]++
-			unpackStatus[strings.ToLower(hi.UnpackStatus.String())]++
-
-			// status is 'status/reason' such as 'success/health'
-			parts := strings.Split(strings.ToLower(hi.Status), "/")
-			status := parts[0]
-			reason := parts[1]
-			if statuses[status] == nil {
-				statuses[status] = map[string]uint64{}
+		completed := c.completedHistory(history)
+		for _, hi := range completed {
+			for _, script := range hi.ScriptStatuses {
+				c.historyScriptStatusTotal.WithLabelValues(withHidden(c.hiddenLabel(&hi), script.Name, strings.ToLower(script.Status))...).Inc()
 			}
-			statuses[status][reason]++
 		}
+		c.historyScriptStatusTotal.Collect(metrics)
+		c.countFailures(completed)
+		c.jobFailuresTotal.Collect(metrics)
 
-		metrics <- prom.MustNewConstMetric(c.historyFileSizeBytes, prom.CounterValue, float64(fileSize))
-		metrics <- prom.MustNewConstMetric(c.historyFileCount, prom.CounterValue, float64(fileCount))
-		metrics <- prom.MustNewConstMetric(c.historyRemainingFileCount, prom.CounterValue, float64(remainingCount))
-		metrics <- prom.MustNewConstMetric(c.historyArticleCount, prom.CounterValue, float64(articleCount))
-		metrics <- prom.MustNewConstMetric(c.historySuccessArticleCount, prom.CounterValue, float64(articleSuccessCount))
-		metrics <- prom.MustNewConstMetric(c.historyFailedArticleCount, prom.CounterValue, float64(articleFailureCount))
-		metrics <- prom.MustNewConstMetric(c.historyDownloadTime, prom.CounterValue, float64(downloadTime))
-		metrics <- prom.MustNewConstMetric(c.historyDownloadSizeBytes, prom.CounterValue, float64(downloadSize))
-		metrics <- prom.MustNewConstMetric(c.historyPostTime, prom.CounterValue, float64(postTime))
-		metrics <- prom.MustNewConstMetric(c.historyParTime, prom.CounterValue, float64(parTime))
-		metrics <- prom.MustNewConstMetric(c.historyRepairTime, prom.CounterValue, float64(repairTime))
-		metrics <- prom.MustNewConstMetric(c.historyUnpackTime, prom.CounterValue, float64(unpackTime))
-		sendConstMapMetric(metrics, c.historyCategoryCount, prom.CounterValue, categories)
-		sendConstMapMapMetric(metrics, c.historyStatusCount, prom.CounterValue, statuses)
-		sendConstMapMetric(metrics, c.historyParStatusCount, prom.CounterValue, parStatus)
-		sendConstMapMetric(metrics, c.historyUnpackStatusCount, prom.CounterValue, unpackStatus)
+		cfgWg.Wait()
+		if cfgErr {
+			return
+		}
+		c.collectRetention(metrics, &config, history)
+		for hidden, items := range partitions {
+			c.collectPar(metrics, &config, items, hidden)
+		}
 	}()
 
+	c.collectBenchmarks(metrics)
+
 	wg.Wait()
 	cfgWg.Wait()
+
+	c.unknownEnums.Collect(metrics)
+	c.decodeRepairs.Collect(metrics)
+}
+
+// countUnknownEnums counts distinct enum values in history that this exporter
+// doesn't know about, logging each new one once
+func (c *NZBGetCollector) countUnknownEnums(history []History) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, hi := range history {
+		for _, unknown := range hi.UnknownEnums {
+			if !c.unknownEnumsSeen[unknown] {
+				c.unknownEnumsSeen[unknown] = true
+				c.unknownEnums.WithLabelValues(unknown.Type).Inc()
+				log.WithField("type", unknown.Type).
+					WithField("value", unknown.Value).
+					Warn("unknown enum value in history")
+			}
+		}
+	}
+}
+
+// partitionHistory splits history items by the value of the hidden label.
+// Hidden items are only included when requested, and otherwise all items are
+// in a single partition with an empty value, as the series don't have the
+// label. Each history collector is called once per partition with its items
+// and the hidden label value to add to every series
+func (c *NZBGetCollector) partitionHistory(history []History) map[string][]History {
+	if !c.Config.HistoryHidden {
+		return map[string][]History{"": history}
+	}
+	partitions := map[string][]History{"false": nil, "true": nil}
+	for _, hi := range history {
+		hidden := c.hiddenLabel(&hi)
+		partitions[hidden] = append(partitions[hidden], hi)
+	}
+	return partitions
+}
+
+// hiddenLabel returns the hidden label value of a history item, or an empty
+// value when hidden history records aren't included
+func (c *NZBGetCollector) hiddenLabel(hi *History) string {
+	if !c.Config.HistoryHidden {
+		return ""
+	}
+	return strconv.FormatBool(hi.Hidden())
+}
+
+// historyLabels returns the label names of a history series, followed by the
+// hidden label only when hidden history records are included. This leaves the
+// series unchanged for anyone not using --history-hidden
+func (c *ExporterConfig) historyLabels(labels ...string) []string {
+	if c.HistoryHidden {
+		return append(labels, "hidden")
+	}
+	return labels
+}
+
+// withHidden appends the hidden label value to the label values of a history
+// series, unless it is empty
+func withHidden(hidden string, labels ...string) []string {
+	if hidden == "" {
+		return labels
+	}
+	return append(labels, hidden)
+}
+
+// collectHistory exports aggregates of history items
+func (c *NZBGetCollector) collectHistory(metrics chan<- prom.Metric, history []History, hidden string) {
+	var (
+		fileSize int64
+		fileCount,
+		remainingCount,
+		articleCount,
+		articleSuccessCount,
+		articleFailureCount,
+		downloadTime uint64
+		downloadSize int64
+		postTime,
+		parTime,
+		repairTime,
+		unpackTime uint64
+
+		categories   = map[string]uint64{}
+		statuses     = map[string]map[string]uint64{}
+		parStatus    = map[string]uint64{}
+		unpackStatus = map[string]uint64{}
+		scriptStatus = map[string]map[string]uint64{}
+		deleteStatus = map[string]map[string]uint64{}
+		markStatus   = map[string]map[string]uint64{}
+		moveStatus   = map[string]map[string]uint64{}
+		urlStatus    = map[string]map[string]uint64{}
+		retryData    = map[string]uint64{}
+		deleted      = map[string]uint64{}
+		postAge      = newHistogramVec(postAgeBuckets)
+		health       = newHistogramVec(healthBuckets)
+		nearCritical = map[string]uint64{}
+	)
+
+	for _, hi := range history {
+		fileSize += hi.FileSize
+		fileCount += hi.FileCount
+		remainingCount += hi.RemainingFileCount
+		articleCount += hi.TotalArticles
+		articleSuccessCount += hi.SuccessArticles
+		articleFailureCount += hi.FailedArticles
+		downloadTime += hi.DownloadTimeSec
+		downloadSize += hi.DownloadedSize
+		postTime += hi.PostTotalTimeSec
+		parTime += hi.ParTimeSec
+		repairTime += hi.RepairTimeSec
+		unpackTime += hi.UnpackTimeSec
+
+		categories[hi.Category]++
+		if deleteStatus[hi.Category] == nil {
+			deleteStatus[hi.Category] = map[string]uint64{}
+			markStatus[hi.Category] = map[string]uint64{}
+			moveStatus[hi.Category] = map[string]uint64{}
+			urlStatus[hi.Category] = map[string]uint64{}
+		}
+		deleteStatus[hi.Category][strings.ToLower(hi.DeleteStatus.String())]++
+		markStatus[hi.Category][strings.ToLower(hi.MarkStatus.String())]++
+		moveStatus[hi.Category][strings.ToLower(hi.MoveStatus.String())]++
+		urlStatus[hi.Category][strings.ToLower(hi.URLStatus.String())]++
+		if hi.RetryData {
+			retryData[hi.Category]++
+		}
+		if hi.Deleted {
+			deleted[hi.Category]++
+		}
+		parStatus[strings.ToLower(hi.ParStatus.String())]++
+		unpackStatus[strings.ToLower(hi.UnpackStatus.String())]++
+
+		status := strings.ToLower(hi.StatusType.String())
+		reason := strings.ToLower(hi.StatusReason.String())
+		if statuses[status] == nil {
+			statuses[status] = map[string]uint64{}
+		}
+		statuses[status][reason]++
+
+		for _, script := range hi.ScriptStatuses {
+			scriptStatusName := strings.ToLower(script.Status)
+			if scriptStatus[scriptStatusName] == nil {
+				scriptStatus[scriptStatusName] = map[string]uint64{}
+			}
+			scriptStatus[scriptStatusName][script.Name]++
+		}
+
+		if hi.Kind != KindURL && hi.TotalArticles > 0 {
+			health.observe(float64(hi.Health)/1000, withHidden(hidden, hi.Category)...)
+			if _, ok := nearCritical[hi.Category]; !ok {
+				nearCritical[hi.Category] = 0
+			}
+			if nearCriticalHealth(hi.Health, hi.CriticalHealth, c.Config.HealthMargin) {
+				nearCritical[hi.Category]++
+			}
+		}
+
+		if hi.MinPostTime.Unix() > 0 && hi.HistoryTime.After(hi.MinPostTime) {
+			age := hi.HistoryTime.Sub(hi.MinPostTime)
+			postAge.observe(age.Seconds(), withHidden(hidden, hi.Category, status)...)
+		}
+	}
+
+	metrics <- prom.MustNewConstMetric(c.historyFileSizeBytes, prom.CounterValue, float64(fileSize), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyFileCount, prom.CounterValue, float64(fileCount), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyRemainingFileCount, prom.CounterValue, float64(remainingCount), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyArticleCount, prom.CounterValue, float64(articleCount), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historySuccessArticleCount, prom.CounterValue, float64(articleSuccessCount), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyFailedArticleCount, prom.CounterValue, float64(articleFailureCount), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyDownloadTime, prom.CounterValue, float64(downloadTime), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyDownloadSizeBytes, prom.CounterValue, float64(downloadSize), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyPostTime, prom.CounterValue, float64(postTime), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyParTime, prom.CounterValue, float64(parTime), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyRepairTime, prom.CounterValue, float64(repairTime), withHidden(hidden)...)
+	metrics <- prom.MustNewConstMetric(c.historyUnpackTime, prom.CounterValue, float64(unpackTime), withHidden(hidden)...)
+	sendConstMapMetric(metrics, c.historyCategoryCount, prom.CounterValue, categories, withHidden(hidden)...)
+	sendConstMapMetric(metrics, c.historyHealthNearCritical, prom.CounterValue, nearCritical, withHidden(hidden)...)
+	sendConstMapMapMetric(metrics, c.historyStatusCount, prom.CounterValue, statuses, withHidden(hidden)...)
+	sendConstMapMetric(metrics, c.historyParStatusCount, prom.CounterValue, parStatus, withHidden(hidden)...)
+	sendConstMapMetric(metrics, c.historyUnpackStatusCount, prom.CounterValue, unpackStatus, withHidden(hidden)...)
+	sendConstMapMapMetric(metrics, c.historyScriptStatusCount, prom.CounterValue, scriptStatus, withHidden(hidden)...)
+	sendConstMapMapMetric(metrics, c.historyDeleteStatusCount, prom.CounterValue, deleteStatus, withHidden(hidden)...)
+	sendConstMapMapMetric(metrics, c.historyMarkStatusCount, prom.CounterValue, markStatus, withHidden(hidden)...)
+	sendConstMapMapMetric(metrics, c.historyMoveStatusCount, prom.CounterValue, moveStatus, withHidden(hidden)...)
+	sendConstMapMapMetric(metrics, c.historyURLStatusCount, prom.CounterValue, urlStatus, withHidden(hidden)...)
+	sendConstMapMetric(metrics, c.historyRetryDataCount, prom.CounterValue, retryData, withHidden(hidden)...)
+	sendConstMapMetric(metrics, c.historyDeletedCount, prom.CounterValue, deleted, withHidden(hidden)...)
+	postAge.send(metrics, c.historyPostAge)
+	health.send(metrics, c.historyHealth)
 }
 
 func (c *NZBGetCollector) getApi(endpoint string, out interface{}) error {
@@ -481,6 +1261,41 @@ func (c *NZBGetCollector) getApi(endpoint string, out interface{}) error {
 	if err != nil {
 		return err
 	}
+	return c.doApi(req, endpoint, out)
+}
+
+// callApi calls a JSON-RPC method with positional parameters. The parameters
+// are never logged as they may contain credentials
+func (c *NZBGetCollector) callApi(method string, params []interface{}, out interface{}) error {
+	// Remove right-trailing slashes, otherwise NZBGet will 404
+	host := strings.TrimRight(c.Config.Host, "/")
+
+	u, err := url.Parse(host + "/jsonrpc")
+	if err != nil {
+		return err
+	}
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"method": method,
+		"params": params,
+	})
+	if err != nil {
+		return err
+	}
+	log.WithField("url", u.String()).
+		WithField("method", method).
+		Debug("POST api")
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.doApi(req, method, out)
+}
+
+func (c *NZBGetCollector) doApi(req *http.Request, endpoint string, out interface{}) error {
 	if c.Config.Username != "" && c.Config.Password != "" {
 		req.SetBasicAuth(c.Config.Username, c.Config.Password)
 	}
@@ -489,6 +1304,7 @@ func (c *NZBGetCollector) getApi(endpoint string, out interface{}) error {
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("nzbget api response %d %s",
 			resp.StatusCode, http.StatusText(resp.StatusCode),
@@ -499,8 +1315,26 @@ func (c *NZBGetCollector) getApi(endpoint string, out interface{}) error {
 	if err != nil {
 		return err
 	}
+	if response.Error != nil {
+		return response.Error
+	}
+
+	result := response.Result
+	if c.Config.TolerantDecode {
+		var repairs []string
+		result, repairs, err = repairNumbers(result, reflect.TypeOf(out))
+		if err != nil {
+			return err
+		}
+		for _, field := range repairs {
+			log.WithField("endpoint", endpoint).
+				WithField("field", field).
+				Debug("repaired out of range integer")
+			c.decodeRepairs.WithLabelValues(endpoint, field).Inc()
+		}
+	}
 
-	return json.Unmarshal(response.Result, out)
+	return json.Unmarshal(result, out)
 }
 
 func sendConstMapMetric(metrics chan<- prom.Metric, desc *prom.Desc, valueType prom.ValueType, values map[string]uint64, labelValues ...string) {
@@ -517,14 +1351,22 @@ func sendConstMapMapMetric(metrics chan<- prom.Metric, desc *prom.Desc, valueTyp
 }
 
 func (c *NZBGetCollector) Describe(descr chan<- *prom.Desc) {
+	descr <- c.version
+	descr <- c.apiFeature
+
 	descr <- c.articleCache
+	descr <- c.averageDownloadRate
 	descr <- c.diskSpaceFree
 	descr <- c.diskSpaceMin
+	descr <- c.diskSpaceTotal
 	descr <- c.downloadLimit
 	descr <- c.downloadPaused
+	descr <- c.downloadRate
 	descr <- c.downloadTimeSec
 	descr <- c.downloadedSize
 	descr <- c.forcedSize
+	descr <- c.interDiskSpaceFree
+	descr <- c.interDiskSpaceTotal
 	descr <- c.postJobCount
 	descr <- c.postPaused
 	descr <- c.quotaDay
@@ -538,8 +1380,42 @@ func (c *NZBGetCollector) Describe(descr chan<- *prom.Desc) {
 	descr <- c.threadCount
 	descr <- c.urlCount
 
+	descr <- c.sysInfo
+	descr <- c.sysInfoNetwork
+	descr <- c.sysInfoTool
+	descr <- c.sysInfoToolAvailable
+	descr <- c.sysInfoLibrary
+
+	descr <- c.systemHealthy
+	descr <- c.systemHealthAlerts
+
+	descr <- c.extensionInfo
+	descr <- c.extensionEnabled
+
 	descr <- c.newsServerActive
 	descr <- c.newsServerBytes
+	descr <- c.newsServerStateChanges
+	descr <- c.newsServerInactiveTime
+	descr <- c.newsServerLastTransition
+	descr <- c.newsServerRetention
+	descr <- c.newsServerRetentionExceeded
+	descr <- c.newsServerRetentionSuccess
+	descr <- c.newsServerRetentionFailed
+	descr <- c.newsServerRetentionFailure
+	descr <- c.newsServerBlockSize
+	descr <- c.newsServerBlockPurchased
+	descr <- c.newsServerBlockUsed
+	descr <- c.newsServerBlockRemaining
+	descr <- c.newsServerBlockExhaustion
+
+	descr <- c.benchmarkDiskThroughput
+	descr <- c.benchmarkDiskDuration
+	descr <- c.benchmarkDiskLastRun
+	descr <- c.benchmarkDiskSuccess
+	descr <- c.benchmarkServerThroughput
+	descr <- c.benchmarkServerDuration
+	descr <- c.benchmarkServerLastRun
+	descr <- c.benchmarkServerSuccess
 
 	descr <- c.historyCategoryCount
 	descr <- c.historyFileSizeBytes
@@ -557,6 +1433,54 @@ func (c *NZBGetCollector) Describe(descr chan<- *prom.Desc) {
 	descr <- c.historyStatusCount
 	descr <- c.historyParStatusCount
 	descr <- c.historyUnpackStatusCount
+	descr <- c.historyPostAge
+	descr <- c.historyScriptStatusCount
+	descr <- c.historyDeleteStatusCount
+	descr <- c.historyMarkStatusCount
+	descr <- c.historyMoveStatusCount
+	descr <- c.historyURLStatusCount
+	descr <- c.historyRetryDataCount
+	descr <- c.historyDeletedCount
+	descr <- c.historyDupeModeCount
+	descr <- c.historyDupeDeletedCount
+	descr <- c.historyDupeHiddenCount
+	descr <- c.historyDupeKeyRetried
+	descr <- c.historyDupeWastedBytes
+	descr <- c.historyURLFetchCount
+	descr <- c.queueURLCount
+	descr <- c.queueURLOldestAge
+	descr <- c.historyIndexerCount
+	descr <- c.historyIndexerHealthDeleted
+	descr <- c.historyIndexerFailureRatio
+	descr <- c.historyIndexerHealthDeletedRatio
+	descr <- c.historyIndexerHealth
+	descr <- c.historyIndexerSize
+	descr <- c.historyParamLabelCount
+	descr <- c.historyParamLabelSize
+	descr <- c.historyParamLabelTime
+	descr <- c.historyRecentFailure
+	descr <- c.historyWindowCount
+	descr <- c.historyWindowSize
+	descr <- c.historyWindowSuccess
+	descr <- c.historyHealth
+	descr <- c.historyHealthNearCritical
+	descr <- c.queueHealth
+	descr <- c.queueHealthNearCritical
+	descr <- c.queueHealthHeadroom
+	descr <- c.historyParPhaseTime
+	descr <- c.historyParExtraBlocks
+	descr <- c.historyParHealthCritical
+	descr <- c.historyParCheckedCount
+	descr <- c.historyParRepairCount
+	descr <- c.historyParRepairRatio
+	descr <- c.newsServerParCheckedCount
+	descr <- c.newsServerParRepairCount
+	descr <- c.newsServerParRepairRatio
+
+	c.unknownEnums.Describe(descr)
+	c.decodeRepairs.Describe(descr)
+	c.historyScriptStatusTotal.Describe(descr)
+	c.jobFailuresTotal.Describe(descr)
 }
 
 var _ prom.Collector = &NZBGetCollector{}
@@ -1,5 +1,7 @@
 package main
 
+import "time"
+
 type ExporterConfig struct {
 	LogLevel  string `long:"log-level" description:"log verbosity level (trace, debug, info, warn, error, fatal)" env:"LOG_LEVEL" default:"info"`
 	Namespace string `long:"namespace" description:"metric name prefix" default:"nzbget" env:"NZBGET_METRIC_NAMESPACE"`
@@ -7,4 +9,39 @@ type ExporterConfig struct {
 	Host      string `short:"h" long:"host" description:"nzbget host to export metrics for" required:"true" env:"NZBGET_HOST"`
 	Username  string `short:"u" long:"username" description:"nzbget username for basicauth" env:"NZBGET_USERNAME"`
 	Password  string `short:"p" long:"password" description:"nzbget password for basicauth" env:"NZBGET_PASSWORD"`
+
+	TolerantDecode bool `long:"tolerant-decode" description:"repair out of range integers in api responses from NZBGet older than v21.1 instead of failing the scrape" env:"NZBGET_TOLERANT_DECODE"`
+
+	CollectSysInfo bool `long:"collect-sysinfo" description:"export host operating system, network addresses and tool versions from the nzbget-ng 'sysinfo' method" env:"NZBGET_COLLECT_SYSINFO"`
+
+	HistoryHidden bool `long:"history-hidden" description:"include hidden history records, such as duplicates hidden by dupe handling, and add a hidden label to all history series to tell them apart. required for history_dupe_hidden_count" env:"NZBGET_HISTORY_HIDDEN"`
+
+	HealthMargin int `long:"health-margin" description:"margin above critical health, in per-mille, within which history and queue items are counted as near failure" default:"20" env:"NZBGET_HEALTH_MARGIN"`
+	HealthJobs   int `long:"health-jobs" description:"export the health headroom of up to this many queue items within the health margin, nearest their critical health first, at most 50. 0 to disable" default:"0" env:"NZBGET_HEALTH_JOBS"`
+
+	HistoryWindows []HistoryWindow `long:"history-window" description:"period of recent history to export completion statistics for, e.g. '1h' or '7d'. can be repeated" default:"1h" default:"24h" default:"7d" env:"NZBGET_HISTORY_WINDOWS" env-delim:","`
+
+	ServerPollInterval time.Duration `long:"server-poll-interval" description:"how often to poll news server state in the background to catch deactivations between scrapes. 0 to disable" default:"5s" env:"NZBGET_SERVER_POLL_INTERVAL"`
+
+	ProbeTimeoutOffset time.Duration `long:"probe-timeout-offset" description:"time taken off the Prometheus scrape timeout for news server probes, to leave time to report a failed probe" default:"0.5s" env:"NZBGET_PROBE_TIMEOUT_OFFSET"`
+
+	BenchmarkAt       []TimeOfDay   `long:"benchmark-at" description:"local time of day (HH:MM) to run disk and news server speed benchmarks. benchmarks are disabled unless set. can be repeated" env:"NZBGET_BENCHMARK_AT" env-delim:","`
+	BenchmarkNZB      string        `long:"benchmark-nzb" description:"url of the nzb file to download for news server speed benchmarks. server benchmarks are skipped unless set" env:"NZBGET_BENCHMARK_NZB"`
+	BenchmarkDiskDir  string        `long:"benchmark-disk-dir" description:"directory to write to for disk speed benchmarks (default: nzbget InterDir)" env:"NZBGET_BENCHMARK_DISK_DIR"`
+	BenchmarkDiskSize int           `long:"benchmark-disk-size" description:"maximum size of the disk speed benchmark file, in GB" default:"1" env:"NZBGET_BENCHMARK_DISK_SIZE"`
+	BenchmarkTimeout  time.Duration `long:"benchmark-timeout" description:"time limit for each benchmark" default:"60s" env:"NZBGET_BENCHMARK_TIMEOUT"`
+
+	Indexers []IndexerRule `long:"indexer" description:"name the indexer of history items whose url host or nzb filename match a regex, as name:regex, e.g. 'geek:nzbgeek\\.info$'. items matching no rule are labelled with the url host. can be repeated" env:"NZBGET_INDEXERS" env-delim:"\n"`
+
+	ParamLabels    []ParamLabelRule `long:"param-label" description:"label history items by their nzb parameters, as param[=regex]:value, e.g. 'drone:sonarr'. an empty value uses the parameter value. the first matching rule wins. can be repeated" env:"NZBGET_PARAM_LABELS" env-delim:"\n"`
+	ParamLabelName ParamLabelName   `long:"param-label-name" description:"name of the label set by --param-label" default:"client" env:"NZBGET_PARAM_LABEL_NAME"`
+
+	FailureRules []FailureRule `long:"failure-rule" description:"classify why jobs failed by matching a regex against their log messages, as reason:regex, e.g. 'vpn:connection refused'. checked in order before the built-in rules. can be repeated" env:"NZBGET_FAILURE_RULES" env-delim:"\n"`
+
+	RecentFailures       int           `long:"recent-failures" description:"export the names of up to this many of the most recent failed or deleted history items, at most 50. 0 to disable" default:"0" env:"NZBGET_RECENT_FAILURES"`
+	RecentFailuresWindow time.Duration `long:"recent-failures-window" description:"only export failed or deleted history items from within this time" default:"24h" env:"NZBGET_RECENT_FAILURES_WINDOW"`
+	RecentFailuresNames  string        `long:"recent-failures-names" description:"how to export the names of failed history items, and of queue items exported by --health-jobs" choice:"plain" choice:"hash" choice:"redact" default:"plain" env:"NZBGET_RECENT_FAILURES_NAMES"`
+
+	BlockAccounts  []BlockAccount `long:"block-account" description:"prepaid block account for a news server as server:size:purchase-date, e.g. 'Eweka:500GB:2024-03-01'. server is a name or id. can be repeated" env:"NZBGET_BLOCK_ACCOUNTS" env-delim:","`
+	BlockStateFile string         `long:"block-state-file" description:"file to keep block account usage in, so that usage carried over NZBGet volume resets survives exporter restarts. without it, usage falls back to the NZBGet total after a reset and restart" env:"NZBGET_BLOCK_STATE_FILE"`
 }
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// repairNumbers works around the NZBGet (pre-v21.1) unsigned integer bug by
+// fixing integers in a JSON document that don't fit the type they decode into.
+// Negative Hi/Lo halves are reinterpreted as unsigned 32-bit integers, Lo
+// halves holding the full value are split back into Hi/Lo, negative sizes,
+// rates and counts are clamped to 0, and any other out-of-range integers are
+// clamped. The names of repaired fields are returned
+// https://github.com/nzbget/nzbget/issues/693
+func repairNumbers(raw json.RawMessage, t reflect.Type) (json.RawMessage, []string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var repairs []string
+	value = repairValue(value, t, "", &repairs)
+	if len(repairs) == 0 {
+		return raw, nil, nil
+	}
+
+	repaired, err := json.Marshal(value)
+	return repaired, repairs, err
+}
+
+func repairValue(value interface{}, t reflect.Type, key string, repairs *[]string) interface{} {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i := range v {
+			v[i] = repairValue(v[i], elem, key, repairs)
+		}
+		return v
+
+	case map[string]interface{}:
+		for k, inner := range v {
+			v[k] = repairValue(inner, fieldType(t, k), k, repairs)
+		}
+		splitHiLo(v, repairs)
+		return v
+
+	case json.Number:
+		if isHiLo(key) {
+			return repairHiLo(v, key, repairs)
+		}
+		if t == nil {
+			return v
+		}
+		return repairInt(v, t, key, repairs)
+	}
+	return value
+}
+
+// fieldType returns the type of the struct field that a JSON key decodes into
+func fieldType(t reflect.Type, key string) reflect.Type {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		if strings.EqualFold(name, key) {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+func isHiLo(key string) bool {
+	return strings.HasSuffix(key, "Hi") || strings.HasSuffix(key, "Lo")
+}
+
+// repairHiLo reinterprets a negative 32-bit half as unsigned
+func repairHiLo(n json.Number, key string, repairs *[]string) interface{} {
+	val, err := strconv.ParseInt(n.String(), 10, 64)
+	if err != nil || val >= 0 {
+		// Values too big for a half are split by splitHiLo
+		return n
+	}
+	*repairs = append(*repairs, key)
+	if val < math.MinInt32 {
+		return json.Number("0")
+	}
+	return json.Number(strconv.FormatInt(val+(1<<32), 10))
+}
+
+// splitHiLo splits Lo halves containing the full 64-bit value into Hi and Lo
+func splitHiLo(obj map[string]interface{}, repairs *[]string) {
+	for key, value := range obj {
+		n, ok := value.(json.Number)
+		if !ok || !strings.HasSuffix(key, "Lo") {
+			continue
+		}
+		val, err := strconv.ParseUint(n.String(), 10, 64)
+		if err != nil || val <= math.MaxUint32 {
+			continue
+		}
+		*repairs = append(*repairs, key)
+		obj[key] = json.Number(strconv.FormatUint(val&math.MaxUint32, 10))
+		obj[strings.TrimSuffix(key, "Lo")+"Hi"] = json.Number(strconv.FormatUint(val>>32, 10))
+	}
+}
+
+// nonNegativeSuffixes are the suffixes of field names that can never
+// legitimately be negative, such as sizes, rates and counts
+var nonNegativeSuffixes = []string{
+	"Size", "MB", "Rate", "Count", "Articles", "Space", "Cache", "Sec",
+}
+
+func isNonNegative(key string) bool {
+	for _, suffix := range nonNegativeSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// repairInt clamps an integer to the range of the type it decodes into, and
+// negative sizes, rates and counts to 0
+func repairInt(n json.Number, t reflect.Type, key string, repairs *[]string) interface{} {
+	var repaired string
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if strings.HasPrefix(n.String(), "-") {
+			if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil && !isRangeError(err) {
+				return n
+			}
+			repaired = "0"
+		} else if _, err := strconv.ParseUint(n.String(), 10, t.Bits()); isRangeError(err) {
+			repaired = strconv.FormatUint(math.MaxUint64>>(64-t.Bits()), 10)
+		} else {
+			return n
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// ParseInt returns the nearest limit when out of range
+		val, err := strconv.ParseInt(n.String(), 10, t.Bits())
+		if err != nil && !isRangeError(err) {
+			return n
+		}
+		if val < 0 && isNonNegative(key) {
+			val = 0
+		} else if err == nil {
+			return n
+		}
+		repaired = strconv.FormatInt(val, 10)
+	default:
+		return n
+	}
+	*repairs = append(*repairs, key)
+	return json.Number(repaired)
+}
+
+// isRangeError returns true if err is from parsing an integer that doesn't
+// fit, rather than one that isn't an integer at all
+func isRangeError(err error) bool {
+	var numErr *strconv.NumError
+	return errors.As(err, &numErr) && numErr.Err == strconv.ErrRange
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type repairTarget struct {
+	FileSizeLo     uint32
+	FileSizeHi     uint32
+	DownloadRate   int64
+	ThreadCount    int
+	ExtraParBlocks int64
+	Health         uint64
+	Small          uint8
+	Servers        []struct {
+		SuccessArticles int
+	}
+}
+
+func TestRepairNumbers(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		out     string
+		repairs []string
+	}{
+		{
+			name: "in range",
+			in:   `{"FileSizeLo":5,"FileSizeHi":1,"DownloadRate":100,"ExtraParBlocks":-2}`,
+			out:  `{"FileSizeLo":5,"FileSizeHi":1,"DownloadRate":100,"ExtraParBlocks":-2}`,
+		},
+		{
+			name:    "negative lo half",
+			in:      `{"FileSizeLo":-1,"FileSizeHi":0}`,
+			out:     `{"FileSizeLo":4294967295,"FileSizeHi":0}`,
+			repairs: []string{"FileSizeLo"},
+		},
+		{
+			name:    "negative hi half",
+			in:      `{"FileSizeLo":0,"FileSizeHi":-2147483648}`,
+			out:     `{"FileSizeLo":0,"FileSizeHi":2147483648}`,
+			repairs: []string{"FileSizeHi"},
+		},
+		{
+			name:    "lo holding full value",
+			in:      `{"FileSizeLo":8589934597,"FileSizeHi":0}`,
+			out:     `{"FileSizeLo":5,"FileSizeHi":2}`,
+			repairs: []string{"FileSizeLo"},
+		},
+		{
+			name:    "negative rate and count",
+			in:      `{"DownloadRate":-5,"ThreadCount":-1}`,
+			out:     `{"DownloadRate":0,"ThreadCount":0}`,
+			repairs: []string{"DownloadRate", "ThreadCount"},
+		},
+		{
+			name:    "uint overflow",
+			in:      `{"Health":18446744073709551616,"Small":300}`,
+			out:     `{"Health":18446744073709551615,"Small":255}`,
+			repairs: []string{"Health", "Small"},
+		},
+		{
+			name:    "negative uint",
+			in:      `{"Health":-3}`,
+			out:     `{"Health":0}`,
+			repairs: []string{"Health"},
+		},
+		{
+			name:    "nested slice",
+			in:      `{"Servers":[{"SuccessArticles":-7}]}`,
+			out:     `{"Servers":[{"SuccessArticles":0}]}`,
+			repairs: []string{"SuccessArticles"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, repairs, err := repairNumbers(json.RawMessage(test.in), reflect.TypeOf(repairTarget{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(out, &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(test.out), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %s, want %s", out, test.out)
+			}
+
+			sort.Strings(repairs)
+			if !reflect.DeepEqual(repairs, test.repairs) {
+				t.Errorf("got repairs %v, want %v", repairs, test.repairs)
+			}
+		})
+	}
+}
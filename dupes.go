@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// collectDupes exports how many history items are duplicates of one another
+// by dupe key, and how much was downloaded only to be deleted as a duplicate
+func (c *NZBGetCollector) collectDupes(metrics chan<- prom.Metric, history []History) {
+	keys := map[string]uint64{}
+	for _, hi := range history {
+		if hi.DupeKey != "" {
+			keys[hi.DupeKey]++
+		}
+	}
+
+	var (
+		hidden      uint64
+		retriedKeys uint64
+
+		modes       = map[string]map[string]uint64{}
+		deleted     = map[string]map[string]uint64{}
+		deletedSize = map[string]int64{}
+	)
+	for _, count := range keys {
+		if count > 1 {
+			retriedKeys++
+		}
+	}
+	for _, hi := range history {
+		hiddenLabel := c.hiddenLabel(&hi)
+		if modes[hiddenLabel] == nil {
+			modes[hiddenLabel] = map[string]uint64{}
+			deleted[hiddenLabel] = map[string]uint64{}
+		}
+		if hi.DupeKey != "" && keys[hi.DupeKey] > 1 {
+			modes[hiddenLabel][strings.ToLower(hi.DupeMode)]++
+		}
+		if hi.Kind == KindDUP {
+			hidden++
+		}
+		if hi.DeleteStatus == DeleteStatusDupe {
+			deleted[hiddenLabel][hi.Category]++
+			deletedSize[hiddenLabel] += hi.DownloadedSize
+		}
+	}
+
+	metrics <- prom.MustNewConstMetric(c.historyDupeHiddenCount, prom.CounterValue, float64(hidden))
+	metrics <- prom.MustNewConstMetric(c.historyDupeKeyRetried, prom.GaugeValue, float64(retriedKeys))
+	for hiddenLabel, size := range deletedSize {
+		metrics <- prom.MustNewConstMetric(c.historyDupeWastedBytes, prom.CounterValue, float64(size), withHidden(hiddenLabel)...)
+	}
+	for hiddenLabel, counts := range modes {
+		sendConstMapMetric(metrics, c.historyDupeModeCount, prom.CounterValue, counts, withHidden(hiddenLabel)...)
+	}
+	for hiddenLabel, counts := range deleted {
+		sendConstMapMetric(metrics, c.historyDupeDeletedCount, prom.CounterValue, counts, withHidden(hiddenLabel)...)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Extension is an installed extension (script), as returned by the
+// 'loadextensions' method in nzbget-ng
+type Extension struct {
+	Name            string
+	DisplayName     string
+	Version         string
+	PostScript      bool
+	ScanScript      bool
+	QueueScript     bool
+	SchedulerScript bool
+	FeedScript      bool
+}
+
+func (e *Extension) Kinds() []string {
+	var kinds []string
+	for _, kind := range [...]struct {
+		name    string
+		enabled bool
+	}{
+		{"post-processing", e.PostScript},
+		{"scan", e.ScanScript},
+		{"queue", e.QueueScript},
+		{"scheduler", e.SchedulerScript},
+		{"feed", e.FeedScript},
+	} {
+		if kind.enabled {
+			kinds = append(kinds, kind.name)
+		}
+	}
+	return kinds
+}
+
+// extensionName normalises an entry from 'Extensions' or 'ScriptOrder', which
+// can be either a script file or a script within a directory, to the name of
+// the extension
+func extensionName(entry string) string {
+	entry = strings.TrimSpace(entry)
+	if dir, _, ok := strings.Cut(entry, "/"); ok {
+		return dir
+	}
+	return strings.TrimSuffix(entry, path.Ext(entry))
+}
+
+// splitExtensions splits a list of extensions separated by commas or
+// semicolons, removing duplicates
+func splitExtensions(list string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, entry := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ';' }) {
+		if name := extensionName(entry); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (c *NZBGetCollector) collectExtensions(metrics chan<- prom.Metric, config *NZBGetConfig) {
+	var extensions []Extension
+	if c.HasFeature(FeatureExtensions) {
+		err := c.callApi("loadextensions", []interface{}{false}, &extensions)
+		if err != nil {
+			log.WithError(err).Error("api call loadextensions")
+		}
+	}
+
+	// Older versions don't list installed extensions, so fall back to those
+	// named in the config
+	if extensions == nil {
+		seen := map[string]bool{}
+		lists := []string{config.Extensions, config.ScriptOrder}
+		for _, category := range config.Category {
+			lists = append(lists, category.Extensions)
+		}
+		for _, list := range lists {
+			for _, name := range splitExtensions(list) {
+				if !seen[name] {
+					seen[name] = true
+					extensions = append(extensions, Extension{Name: name})
+				}
+			}
+		}
+	}
+
+	for _, ext := range extensions {
+		kinds := ext.Kinds()
+		if len(kinds) == 0 {
+			kinds = []string{"unknown"}
+		}
+		for _, kind := range kinds {
+			metrics <- prom.MustNewConstMetric(c.extensionInfo, prom.GaugeValue, 1, ext.Name, ext.Version, kind)
+		}
+	}
+
+	for _, name := range splitExtensions(config.Extensions) {
+		metrics <- prom.MustNewConstMetric(c.extensionEnabled, prom.GaugeValue, 1, name, "global", "")
+	}
+	for _, category := range config.Category {
+		if category.Name == "" {
+			continue
+		}
+		for _, name := range splitExtensions(category.Extensions) {
+			metrics <- prom.MustNewConstMetric(c.extensionEnabled, prom.GaugeValue, 1, name, "category", category.Name)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// failureLogEntries is the number of most recent log messages fetched for a
+// failed job, as the cause is normally logged near the end
+const failureLogEntries = 200
+
+// failureLogFetches is the most job logs fetched with 'loadlog' per scrape
+const failureLogFetches = 5
+
+// maxPendingFailures is the most failed jobs kept waiting for their log to be
+// fetched
+const maxPendingFailures = 100
+
+// FailureRule classifies the reason a job failed by matching a regex against
+// its log messages, configured as 'reason:regex'
+type FailureRule struct {
+	Reason  string
+	Pattern *regexp.Regexp
+}
+
+func (r *FailureRule) UnmarshalFlag(value string) error {
+	reason, pattern, ok := strings.Cut(value, ":")
+	if !ok || reason == "" || pattern == "" {
+		return fmt.Errorf("failure rule %q must be in the form reason:regex", value)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("failure rule %q: %w", value, err)
+	}
+
+	r.Reason = reason
+	r.Pattern = re
+	return nil
+}
+
+// defaultFailureRules are checked after any configured rules, and match the
+// messages logged by NZBGet itself, and the unrar and 7-Zip output it logs
+// while unpacking. Password failures are checked before unpack failures, as
+// both are logged when an encrypted archive can't be unpacked
+var defaultFailureRules = []FailureRule{
+	{"disk_full", regexp.MustCompile(`(?i)no space left on device|disk (is )?full|not enough (free )?(disk )?space`)},
+	{"password", regexp.MustCompile(`(?i)(wrong|incorrect|invalid) password|password (is )?(incorrect|required|wrong)|encrypted (archive|file|headers)`)},
+	{"crc", regexp.MustCompile(`(?i)crc (error|mismatch|failed)|checksum (error|mismatch)`)},
+	{"missing_articles", regexp.MustCompile(`(?i)due to health [\d.]+% below critical|article .* not found|repair not possible|not enough (par-?)?blocks|(par-?check|repair) failed`)},
+	{"unpack", regexp.MustCompile(`(?i)unpack(ing)? (for )?.*failed|unrar error|7-?zip error|unpack error`)},
+	{"script", regexp.MustCompile(`(?i)script .*(failed|returned|error|exit code)`)},
+}
+
+// classifyFailure returns the reason a job failed from its log messages, or
+// 'other' if no rule matches. Errors are checked before warnings, and the
+// first rule to match wins
+func (c *NZBGetCollector) classifyFailure(messages []LogMessage) string {
+	rules := append(append([]FailureRule{}, c.Config.FailureRules...), defaultFailureRules...)
+	for _, kind := range [...]string{"ERROR", "WARNING"} {
+		for _, rule := range rules {
+			for _, msg := range messages {
+				if msg.Kind == kind && rule.Pattern.MatchString(msg.Text) {
+					return rule.Reason
+				}
+			}
+		}
+	}
+	return "other"
+}
+
+// pendingFailure is a failed job waiting for its log to be classified
+type pendingFailure struct {
+	nzbid    uint64
+	category string
+	log      []LogMessage
+}
+
+// jobLog returns the log messages of a failed job, fetching them with
+// 'loadlog' if they weren't included in the history
+func (c *NZBGetCollector) jobLog(job *pendingFailure) ([]LogMessage, error) {
+	if len(job.log) > 0 {
+		return job.log, nil
+	}
+	var messages []LogMessage
+	err := c.callApi("loadlog", []interface{}{job.nzbid, 0, failureLogEntries}, &messages)
+	return messages, err
+}
+
+// countFailures classifies and counts the reason for each newly failed job.
+// At most failureLogFetches job logs are fetched per scrape, so that a burst of
+// failures can't push the scrape past its timeout. Any more are left for the
+// following scrapes, up to maxPendingFailures. Beyond that the oldest are
+// counted as 'unclassified', as they have likely been pruned from history
+func (c *NZBGetCollector) countFailures(completed []History) {
+	c.mu.Lock()
+	for _, hi := range completed {
+		if hi.StatusType == HistoryStatusFailure {
+			c.pendingFailures = append(c.pendingFailures, pendingFailure{hi.NZBID, hi.Category, hi.Log})
+		}
+	}
+	var dropped []pendingFailure
+	if excess := len(c.pendingFailures) - maxPendingFailures; excess > 0 {
+		dropped = c.pendingFailures[:excess]
+		c.pendingFailures = c.pendingFailures[excess:]
+	}
+	var failures []pendingFailure
+	var fetches int
+	for len(c.pendingFailures) > 0 && fetches < failureLogFetches {
+		job := c.pendingFailures[0]
+		c.pendingFailures = c.pendingFailures[1:]
+		if len(job.log) == 0 {
+			fetches++
+		}
+		failures = append(failures, job)
+	}
+	c.mu.Unlock()
+
+	if len(dropped) > 0 {
+		log.WithField("count", len(dropped)).Warn("too many failed jobs to classify, skipping the oldest")
+	}
+	for _, job := range dropped {
+		c.jobFailuresTotal.WithLabelValues(job.category, "unclassified").Inc()
+	}
+	for _, job := range failures {
+		reason := "other"
+		messages, err := c.jobLog(&job)
+		if err != nil {
+			log.WithError(err).
+				WithField("nzbid", job.nzbid).
+				Warn("api get loadlog")
+		} else {
+			reason = c.classifyFailure(messages)
+		}
+		c.jobFailuresTotal.WithLabelValues(job.category, reason).Inc()
+	}
+}
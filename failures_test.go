@@ -0,0 +1,96 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []LogMessage
+		rules    []FailureRule
+		reason   string
+	}{
+		{
+			name:     "health",
+			messages: []LogMessage{{Kind: "WARNING", Text: "Cancelling download and deleting Show.S01E01 due to health 84.3% below critical 89.2%"}},
+			reason:   "missing_articles",
+		},
+		{
+			name:     "article not found",
+			messages: []LogMessage{{Kind: "WARNING", Text: "Article Show.S01E01.part01.rar [12/50] @ news.example.com (1) failed: article not found"}},
+			reason:   "missing_articles",
+		},
+		{
+			name:     "repair not possible",
+			messages: []LogMessage{{Kind: "ERROR", Text: "Repair not possible for Show.S01E01"}},
+			reason:   "missing_articles",
+		},
+		{
+			name:     "wrong password",
+			messages: []LogMessage{{Kind: "ERROR", Text: "Unrar: The specified password is incorrect."}},
+			reason:   "password",
+		},
+		{
+			name: "password before unpack",
+			messages: []LogMessage{
+				{Kind: "ERROR", Text: "Unpack for Show.S01E01 failed"},
+				{Kind: "ERROR", Text: "Unrar: Encrypted file: CRC failed in show.mkv (password incorrect ?)"},
+			},
+			reason: "password",
+		},
+		{
+			name:     "unpack",
+			messages: []LogMessage{{Kind: "ERROR", Text: "Unpack for Show.S01E01 failed"}},
+			reason:   "unpack",
+		},
+		{
+			name:     "disk full",
+			messages: []LogMessage{{Kind: "ERROR", Text: "Could not write to /downloads/Show.S01E01/show.mkv: No space left on device"}},
+			reason:   "disk_full",
+		},
+		{
+			name:     "script",
+			messages: []LogMessage{{Kind: "ERROR", Text: "Post-process-script Notify.py for Show.S01E01 failed (terminated with unknown status)"}},
+			reason:   "script",
+		},
+		{
+			name: "health and password in other messages",
+			messages: []LogMessage{
+				{Kind: "ERROR", Text: "Could not connect to health.example.com: connection refused"},
+				{Kind: "ERROR", Text: "Server password.example.com requires login"},
+			},
+			reason: "other",
+		},
+		{
+			name: "errors before warnings",
+			messages: []LogMessage{
+				{Kind: "WARNING", Text: "Repair not possible for Show.S01E01"},
+				{Kind: "ERROR", Text: "Unpack for Show.S01E01 failed"},
+			},
+			reason: "unpack",
+		},
+		{
+			name:     "info is ignored",
+			messages: []LogMessage{{Kind: "INFO", Text: "Unpack for Show.S01E01 failed"}},
+			reason:   "other",
+		},
+		{
+			name:     "configured rule first",
+			messages: []LogMessage{{Kind: "ERROR", Text: "Unpack for Show.S01E01 failed: connection refused by vpn"}},
+			rules:    []FailureRule{{"vpn", regexp.MustCompile(`vpn`)}},
+			reason:   "vpn",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &NZBGetCollector{Config: &ExporterConfig{FailureRules: test.rules}}
+			reason := c.classifyFailure(test.messages)
+			if reason != test.reason {
+				t.Errorf("got reason %q, want %q", reason, test.reason)
+			}
+		})
+	}
+}
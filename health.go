@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// systemhealth is only available in nzbget-ng, and reports problems found
+// when validating the config
+
+type SystemHealth struct {
+	Alerts   []HealthAlert
+	Sections []HealthSection
+}
+
+type HealthSection struct {
+	Name    string
+	Alerts  []HealthAlert
+	Options []struct {
+		Name   string
+		Alerts []HealthAlert
+	}
+}
+
+type HealthAlert struct {
+	Severity string
+	Source   string
+	Message  string
+}
+
+// SystemHealthAlert is a single alert flattened out of SystemHealth
+type SystemHealthAlert struct {
+	Severity string `json:"severity"`
+	Section  string `json:"section"`
+	Option   string `json:"option,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (h *SystemHealth) Flatten() []SystemHealthAlert {
+	var alerts []SystemHealthAlert
+	add := func(alert HealthAlert, section, option string) {
+		if section == "" {
+			section = alert.Source
+		}
+		alerts = append(alerts, SystemHealthAlert{
+			Severity: strings.ToLower(alert.Severity),
+			Section:  section,
+			Option:   option,
+			Message:  alert.Message,
+		})
+	}
+
+	for _, alert := range h.Alerts {
+		add(alert, "", "")
+	}
+	for _, section := range h.Sections {
+		for _, alert := range section.Alerts {
+			add(alert, section.Name, "")
+		}
+		for _, option := range section.Options {
+			for _, alert := range option.Alerts {
+				add(alert, section.Name, option.Name)
+			}
+		}
+	}
+	return alerts
+}
+
+func (c *NZBGetCollector) collectSystemHealth(metrics chan<- prom.Metric) {
+	var health SystemHealth
+	err := c.getApi("systemhealth", &health)
+	if err != nil {
+		// Config alerts are auxiliary, so losing them mustn't fail the scrape
+		log.WithError(err).Error("api get systemhealth")
+		return
+	}
+
+	healthy := true
+	alerts := map[string]map[string]uint64{}
+	for _, alert := range health.Flatten() {
+		if alert.Severity == "error" {
+			healthy = false
+		}
+		if alerts[alert.Severity] == nil {
+			alerts[alert.Severity] = map[string]uint64{}
+		}
+		alerts[alert.Severity][alert.Section]++
+	}
+
+	metrics <- prom.MustNewConstMetric(c.systemHealthy, prom.GaugeValue, floatOf(healthy))
+	sendConstMapMapMetric(metrics, c.systemHealthAlerts, prom.GaugeValue, alerts)
+}
+
+// SystemHealthAlerts handles /systemhealth, returning the text of the current
+// nzbget-ng system health alerts as JSON
+func (c *NZBGetCollector) SystemHealthAlerts(w http.ResponseWriter, r *http.Request) {
+	var health SystemHealth
+	err := c.getApi("systemhealth", &health)
+	if err != nil {
+		log.WithError(err).Error("api get systemhealth")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	alerts := health.Flatten()
+	if alerts == nil {
+		alerts = []SystemHealthAlert{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(alerts)
+	if err != nil {
+		log.WithError(err).Error("encode systemhealth")
+	}
+}
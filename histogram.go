@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// histogramVec accumulates observations for a set of label values, to be sent
+// as constant histograms
+type histogramVec struct {
+	buckets []float64
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels []string
+	count  uint64
+	sum    float64
+	counts []uint64
+}
+
+func newHistogramVec(buckets []float64) *histogramVec {
+	return &histogramVec{
+		buckets: buckets,
+		series:  map[string]*histogramSeries{},
+	}
+}
+
+func (v *histogramVec) observe(value float64, labels ...string) {
+	key := strings.Join(labels, "\xff")
+	series := v.series[key]
+	if series == nil {
+		series = &histogramSeries{
+			labels: labels,
+			counts: make([]uint64, len(v.buckets)),
+		}
+		v.series[key] = series
+	}
+
+	series.count++
+	series.sum += value
+	// Buckets are sorted, so find the first one the value fits in
+	i := sort.SearchFloat64s(v.buckets, value)
+	if i < len(v.buckets) {
+		series.counts[i]++
+	}
+}
+
+func (v *histogramVec) send(metrics chan<- prom.Metric, desc *prom.Desc) {
+	for _, series := range v.series {
+		// Constant histograms expect cumulative bucket counts
+		buckets := make(map[float64]uint64, len(v.buckets))
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += series.counts[i]
+			buckets[upper] = cumulative
+		}
+		metrics <- prom.MustNewConstHistogram(desc, series.count, series.sum, buckets, series.labels...)
+	}
+}
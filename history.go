@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -15,7 +16,9 @@ type (
 		RetryData          bool
 		HistoryTime        time.Time `json:"-"`
 		Status             string
-		Log                []interface{}
+		StatusType         HistoryStatus       `json:"-"`
+		StatusReason       HistoryStatusReason `json:"-"`
+		Log                []LogMessage
 		NZBName            string
 		Kind               HistoryKind
 		URL                string
@@ -50,10 +53,19 @@ type (
 		RepairTimeSec    uint64
 		UnpackTimeSec    uint64
 		MessageCount     uint64
-		ExtraParBlocks   uint64
+		ExtraParBlocks   int64 // negative when donated to duplicates
 		Parameters       []Parameters
 		ScriptStatuses   []ScriptStatus
 		ServerStats      []ServerStats
+
+		// UnknownEnums holds any enum values that weren't recognised when
+		// decoding, such as those added in newer NZBGet versions
+		UnknownEnums []UnknownEnum `json:"-"`
+	}
+
+	UnknownEnum struct {
+		Type  string
+		Value string
 	}
 
 	Parameters struct {
@@ -71,8 +83,70 @@ type (
 		SuccessArticles int
 		FailedArticles  int
 	}
+
+	// LogMessage is a job log entry, from History.Log or 'loadlog'
+	LogMessage struct {
+		ID   int
+		Kind string
+		Time int64
+		Text string
+	}
+)
+
+//go:generate enumerx -type=HistoryStatus -trimprefix=@type -transform=snake_upper
+type HistoryStatus int
+
+const (
+	HistoryStatusUnknown HistoryStatus = iota
+	HistoryStatusSuccess
+	HistoryStatusWarning
+	HistoryStatusFailure
+	HistoryStatusDeleted
+)
+
+//go:generate enumerx -type=HistoryStatusReason -trimprefix=@type -transform=snake_upper
+type HistoryStatusReason int
+
+const (
+	HistoryStatusReasonUnknown HistoryStatusReason = iota
+	HistoryStatusReasonAll
+	HistoryStatusReasonUnpack
+	HistoryStatusReasonPar
+	HistoryStatusReasonHealth
+	HistoryStatusReasonGood
+	HistoryStatusReasonMark
+	HistoryStatusReasonHidden
+	HistoryStatusReasonScript
+	HistoryStatusReasonSpace
+	HistoryStatusReasonPassword
+	HistoryStatusReasonDamaged
+	HistoryStatusReasonRepairable
+	HistoryStatusReasonSkipped
+	HistoryStatusReasonMove
+	HistoryStatusReasonScan
+	HistoryStatusReasonBad
+	HistoryStatusReasonFetch
+	HistoryStatusReasonManual
+	HistoryStatusReasonDupe
+	HistoryStatusReasonCopy
 )
 
+// parseHistoryStatus splits a history status such as 'SUCCESS/ALL' into its
+// status and reason, falling back to unknown for anything unrecognised
+func parseHistoryStatus(s string) (HistoryStatus, HistoryStatusReason) {
+	status, reason, _ := strings.Cut(strings.ToUpper(s), "/")
+
+	parsedStatus, err := HistoryStatusString(status)
+	if err != nil {
+		parsedStatus = HistoryStatusUnknown
+	}
+	parsedReason, err := HistoryStatusReasonString(reason)
+	if err != nil {
+		parsedReason = HistoryStatusReasonUnknown
+	}
+	return parsedStatus, parsedReason
+}
+
 //go:generate enumerx -type=HistoryKind -trimprefix=Kind -json
 type HistoryKind int
 
@@ -80,6 +154,7 @@ const (
 	KindNZB HistoryKind = iota
 	KindURL
 	KindDUP
+	KindUnknown
 )
 
 //go:generate enumerx -type=ParStatus -trimprefix=@type -transform=snake_upper -json
@@ -91,6 +166,7 @@ const (
 	ParStatusRepairPossible
 	ParStatusSuccess
 	ParStatusManual
+	ParStatusUnknown
 )
 
 //go:generate enumerx -type=UnpackStatus -trimprefix=@type -transform=snake_upper -json
@@ -102,6 +178,7 @@ const (
 	UnpackStatusSpace
 	UnpackStatusPassword
 	UnpackStatusSuccess
+	UnpackStatusUnknown
 )
 
 //go:generate enumerx -type=URLStatus -trimprefix=@type -transform=snake_upper -json
@@ -116,6 +193,7 @@ const (
 	URLStatusRetry
 	URLStatusScanSkipped
 	URLStatusScanFailure
+	URLStatusUnknown
 )
 
 //go:generate enumerx -type=MoveStatus -trimprefix=@type -transform=snake_upper -json
@@ -125,6 +203,7 @@ const (
 	MoveStatusNone MoveStatus = iota
 	MoveStatusSuccess
 	MoveStatusFailure
+	MoveStatusUnknown
 )
 
 //go:generate enumerx -type=DeleteStatus -trimprefix=@type -transform=snake_upper -json
@@ -139,6 +218,7 @@ const (
 	DeleteStatusGood
 	DeleteStatusScan
 	DeleteStatusCopy
+	DeleteStatusUnknown
 )
 
 //go:generate enumerx -type=MarkStatus -trimprefix=@type -transform=snake_upper -json
@@ -149,22 +229,65 @@ const (
 	MarkStatusGood
 	MarkStatusBad
 	MarkStatusSuccess
+	MarkStatusUnknown
 )
 
+// Hidden returns true for history records that are hidden from the history
+// unless explicitly requested, which are those kept by duplicate handling
+func (h *History) Hidden() bool {
+	return h.Kind == KindDUP
+}
+
+// parseEnum parses an enum value with the enumerx-generated parser, recording
+// the raw value in the history item and returning unknown if unrecognised.
+// Missing values are left as the zero value
+func parseEnum[T any](h *History, typ, raw string, parse func(string) (T, error), unknown T) T {
+	var zero T
+	if raw == "" {
+		return zero
+	}
+	value, err := parse(raw)
+	if err != nil {
+		h.UnknownEnums = append(h.UnknownEnums, UnknownEnum{typ, raw})
+		return unknown
+	}
+	return value
+}
+
 func (h *History) UnmarshalJSON(b []byte) error {
-	// Unmarshal the struct as normal
+	// Unmarshal the struct as normal, except for enums which are decoded as
+	// strings so that unknown values don't fail the whole history
 	type resultClone History
-	var clone = (*resultClone)(h)
-	err := json.Unmarshal(b, clone)
+	var clone = struct {
+		*resultClone
+		Kind         string
+		ParStatus    string
+		UnpackStatus string
+		URLStatus    string
+		MoveStatus   string
+		DeleteStatus string
+		MarkStatus   string
+	}{resultClone: (*resultClone)(h)}
+	err := json.Unmarshal(b, &clone)
 	if err != nil {
 		return err
 	}
 
+	h.Kind = parseEnum(h, "HistoryKind", clone.Kind, HistoryKindString, KindUnknown)
+	h.ParStatus = parseEnum(h, "ParStatus", clone.ParStatus, ParStatusString, ParStatusUnknown)
+	h.UnpackStatus = parseEnum(h, "UnpackStatus", clone.UnpackStatus, UnpackStatusString, UnpackStatusUnknown)
+	h.URLStatus = parseEnum(h, "URLStatus", clone.URLStatus, URLStatusString, URLStatusUnknown)
+	h.MoveStatus = parseEnum(h, "MoveStatus", clone.MoveStatus, MoveStatusString, MoveStatusUnknown)
+	h.DeleteStatus = parseEnum(h, "DeleteStatus", clone.DeleteStatus, DeleteStatusString, DeleteStatusUnknown)
+	h.MarkStatus = parseEnum(h, "MarkStatus", clone.MarkStatus, MarkStatusString, MarkStatusUnknown)
+
 	type temp struct {
-		FileSizeHi       uint32 `json:"FileSizeHi"`
-		FileSizeLo       uint32 `json:"FileSizeLo"`
-		DownloadedSizeHi uint32 `json:"DownloadedSizeHi"`
-		DownloadedSizeLo uint32 `json:"DownloadedSizeLo"`
+		// nzbget-ng may provide sizes as plain 64-bit values instead, which
+		// are decoded into the struct as normal
+		FileSizeHi       *uint32 `json:"FileSizeHi"`
+		FileSizeLo       *uint32 `json:"FileSizeLo"`
+		DownloadedSizeHi *uint32 `json:"DownloadedSizeHi"`
+		DownloadedSizeLo *uint32 `json:"DownloadedSizeLo"`
 
 		HistoryTime int64 `json:"HistoryTime"`
 		MinPostTime int64 `json:"MinPostTime"`
@@ -177,11 +300,16 @@ func (h *History) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	h.FileSize = joinInt64(values.FileSizeLo, values.FileSizeHi)
-	h.DownloadedSize = joinInt64(values.DownloadedSizeLo, values.DownloadedSizeHi)
+	h.FileSize = pickInt64(&h.FileSize, values.FileSizeLo, values.FileSizeHi)
+	h.DownloadedSize = pickInt64(&h.DownloadedSize, values.DownloadedSizeLo, values.DownloadedSizeHi)
+
+	h.StatusType, h.StatusReason = parseHistoryStatus(h.Status)
+	if h.Status != "" && (h.StatusType == HistoryStatusUnknown || h.StatusReason == HistoryStatusReasonUnknown) {
+		h.UnknownEnums = append(h.UnknownEnums, UnknownEnum{"HistoryStatus", h.Status})
+	}
 
 	h.HistoryTime = time.Unix(values.HistoryTime, 0)
-	h.MinPostTime = time.Unix(values.MaxPostTime, 0)
+	h.MinPostTime = time.Unix(values.MinPostTime, 0)
 	h.MaxPostTime = time.Unix(values.MaxPostTime, 0)
 
 	return nil
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// IndexerRule names the indexer of history items whose url host or nzb
+// filename match a regex, configured as 'name:regex'
+type IndexerRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+func (r *IndexerRule) UnmarshalFlag(value string) error {
+	name, pattern, ok := strings.Cut(value, ":")
+	if !ok || name == "" || pattern == "" {
+		return fmt.Errorf("indexer %q must be in the form name:regex", value)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("indexer %q: %w", value, err)
+	}
+
+	r.Name = name
+	r.Pattern = re
+	return nil
+}
+
+// indexerOf returns the indexer a history item was downloaded from: the first
+// matching configured rule, else the host of the url the nzb was fetched from
+func (c *NZBGetCollector) indexerOf(hi *History) string {
+	host := ""
+	if hi.URL != "" {
+		host = indexerHost(hi.URL)
+	}
+	for _, rule := range c.Config.Indexers {
+		if (host != "" && rule.Pattern.MatchString(host)) || rule.Pattern.MatchString(hi.NZBFilename) {
+			return rule.Name
+		}
+	}
+	if host == "" {
+		return "unknown"
+	}
+	return host
+}
+
+type indexerStats struct {
+	statuses      map[string]uint64
+	count         uint64
+	failed        uint64
+	healthDeleted uint64
+	health        uint64
+	healthCount   uint64
+	size          int64
+}
+
+// collectIndexers exports the outcome of history items per indexer, to show
+// which indexers' nzbs fail most
+func (c *NZBGetCollector) collectIndexers(metrics chan<- prom.Metric, history []History, hidden string) {
+	indexers := map[string]*indexerStats{}
+	for _, hi := range history {
+		indexer := c.indexerOf(&hi)
+		st := indexers[indexer]
+		if st == nil {
+			st = &indexerStats{statuses: map[string]uint64{}}
+			indexers[indexer] = st
+		}
+
+		st.count++
+		st.statuses[strings.ToLower(hi.StatusType.String())]++
+		if hi.StatusType == HistoryStatusFailure {
+			st.failed++
+		}
+		if hi.DeleteStatus == DeleteStatusHealth {
+			st.healthDeleted++
+		}
+		// URLs that failed to fetch have no articles, so no health
+		if hi.Kind != KindURL && hi.TotalArticles > 0 {
+			st.health += hi.Health
+			st.healthCount++
+		}
+		st.size += hi.DownloadedSize
+	}
+
+	for indexer, st := range indexers {
+		count := float64(st.count)
+		sendConstMapMetric(metrics, c.historyIndexerCount, prom.CounterValue, st.statuses, withHidden(hidden, indexer)...)
+		metrics <- prom.MustNewConstMetric(c.historyIndexerHealthDeleted, prom.CounterValue, float64(st.healthDeleted), withHidden(hidden, indexer)...)
+		metrics <- prom.MustNewConstMetric(c.historyIndexerFailureRatio, prom.GaugeValue, float64(st.failed)/count, withHidden(hidden, indexer)...)
+		metrics <- prom.MustNewConstMetric(c.historyIndexerHealthDeletedRatio, prom.GaugeValue, float64(st.healthDeleted)/count, withHidden(hidden, indexer)...)
+		if st.healthCount > 0 {
+			health := float64(st.health) / float64(st.healthCount) / 1000
+			metrics <- prom.MustNewConstMetric(c.historyIndexerHealth, prom.GaugeValue, health, withHidden(hidden, indexer)...)
+		}
+		metrics <- prom.MustNewConstMetric(c.historyIndexerSize, prom.CounterValue, float64(st.size), withHidden(hidden, indexer)...)
+	}
+}
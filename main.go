@@ -64,20 +64,39 @@ func main() {
 		log.WithError(err).Warn("failed to get nzbget version")
 	} else {
 		log.Infof("nzbget version %s", version)
+		collector.setVersion(version)
 	}
 
-	promHandler := promhttp.Handler()
-	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
-		log.WithField("remote", r.RemoteAddr).
-			Info(fmt.Sprintf("%s %s", r.Method, r.URL.Path))
-		promHandler.ServeHTTP(w, r)
+	if config.BlockStateFile != "" {
+		err = collector.LoadBlockUsage()
+		if err != nil {
+			log.WithError(err).Fatal("load block account state")
+		}
+	}
+
+	if config.ServerPollInterval > 0 {
+		go collector.PollNewsServers(config.ServerPollInterval)
+	}
+
+	if len(config.BenchmarkAt) > 0 {
+		go collector.RunBenchmarks()
 	}
 
 	log.Info("serving metrics at " + config.Listen)
 
-	http.Handle("/metrics", handler)
+	http.Handle("/metrics", logRequest(promhttp.Handler()))
+	http.Handle("/probe/newsserver", logRequest(http.HandlerFunc(collector.ProbeNewsServer)))
+	http.Handle("/systemhealth", logRequest(http.HandlerFunc(collector.SystemHealthAlerts)))
 	err = http.ListenAndServe(config.Listen, nil)
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.WithError(err).Panic("listenandserve")
 	}
 }
+
+func logRequest(handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.WithField("remote", r.RemoteAddr).
+			Info(fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+		handler.ServeHTTP(w, r)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -12,6 +13,17 @@ import (
 type Response struct {
 	Version string          `json:"version"`
 	Result  json.RawMessage `json:"result"`
+	Error   *ResponseError  `json:"error"`
+}
+
+type ResponseError struct {
+	Name    string `json:"name"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("nzbget api error %d: %s", e.Code, e.Message)
 }
 
 type Status struct {
@@ -23,6 +35,11 @@ type Status struct {
 	MonthSize      int64 `json:"-"`
 	RemainingSize  int64 `json:"-"`
 
+	// Only provided by nzbget-ng
+	TotalDiskSpace      int64 `json:"-"`
+	FreeInterDiskSpace  int64 `json:"-"`
+	TotalInterDiskSpace int64 `json:"-"`
+
 	AverageDownloadRate int64 `json:"AverageDownloadRate"`
 	DownloadLimit       int64 `json:"DownloadLimit"`
 	DownloadRate        int64 `json:"DownloadRate"`
@@ -62,21 +79,42 @@ func (s *Status) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	// Sizes are split into Hi/Lo halves, but nzbget-ng also provides some as
+	// plain 64-bit values. Pointers are used to tell which are present
 	type temp struct {
-		ArticleCacheHi   uint32 `json:"ArticleCacheHi"`
-		ArticleCacheLo   uint32 `json:"ArticleCacheLo"`
-		DaySizeHi        uint32 `json:"DaySizeHi"`
-		DaySizeLo        uint32 `json:"DaySizeLo"`
-		DownloadedSizeHi uint32 `json:"DownloadedSizeHi"`
-		DownloadedSizeLo uint32 `json:"DownloadedSizeLo"`
-		ForcedSizeHi     uint32 `json:"ForcedSizeHi"`
-		ForcedSizeLo     uint32 `json:"ForcedSizeLo"`
-		FreeDiskSpaceHi  uint32 `json:"FreeDiskSpaceHi"`
-		FreeDiskSpaceLo  uint32 `json:"FreeDiskSpaceLo"`
-		MonthSizeHi      uint32 `json:"MonthSizeHi"`
-		MonthSizeLo      uint32 `json:"MonthSizeLo"`
-		RemainingSizeHi  uint32 `json:"RemainingSizeHi"`
-		RemainingSizeLo  uint32 `json:"RemainingSizeLo"`
+		ArticleCache     *int64  `json:"ArticleCache"`
+		ArticleCacheHi   *uint32 `json:"ArticleCacheHi"`
+		ArticleCacheLo   *uint32 `json:"ArticleCacheLo"`
+		DaySize          *int64  `json:"DaySize"`
+		DaySizeHi        *uint32 `json:"DaySizeHi"`
+		DaySizeLo        *uint32 `json:"DaySizeLo"`
+		DownloadedSize   *int64  `json:"DownloadedSize"`
+		DownloadedSizeHi *uint32 `json:"DownloadedSizeHi"`
+		DownloadedSizeLo *uint32 `json:"DownloadedSizeLo"`
+		ForcedSize       *int64  `json:"ForcedSize"`
+		ForcedSizeHi     *uint32 `json:"ForcedSizeHi"`
+		ForcedSizeLo     *uint32 `json:"ForcedSizeLo"`
+		FreeDiskSpace    *int64  `json:"FreeDiskSpace"`
+		FreeDiskSpaceHi  *uint32 `json:"FreeDiskSpaceHi"`
+		FreeDiskSpaceLo  *uint32 `json:"FreeDiskSpaceLo"`
+		MonthSize        *int64  `json:"MonthSize"`
+		MonthSizeHi      *uint32 `json:"MonthSizeHi"`
+		MonthSizeLo      *uint32 `json:"MonthSizeLo"`
+		RemainingSize    *int64  `json:"RemainingSize"`
+		RemainingSizeHi  *uint32 `json:"RemainingSizeHi"`
+		RemainingSizeLo  *uint32 `json:"RemainingSizeLo"`
+
+		// nzbget-ng only
+		DownloadRateHi        *uint32 `json:"DownloadRateHi"`
+		DownloadRateLo        *uint32 `json:"DownloadRateLo"`
+		AverageDownloadRateHi *uint32 `json:"AverageDownloadRateHi"`
+		AverageDownloadRateLo *uint32 `json:"AverageDownloadRateLo"`
+		TotalDiskSpaceHi      *uint32 `json:"TotalDiskSpaceHi"`
+		TotalDiskSpaceLo      *uint32 `json:"TotalDiskSpaceLo"`
+		FreeInterDiskSpaceHi  *uint32 `json:"FreeInterDiskSpaceHi"`
+		FreeInterDiskSpaceLo  *uint32 `json:"FreeInterDiskSpaceLo"`
+		TotalInterDiskSpaceHi *uint32 `json:"TotalInterDiskSpaceHi"`
+		TotalInterDiskSpaceLo *uint32 `json:"TotalInterDiskSpaceLo"`
 
 		ServerTime int64 `json:"ServerTime"`
 		ResumeTime int64 `json:"ResumeTime"`
@@ -89,13 +127,20 @@ func (s *Status) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	s.ArticleCache = joinInt64(values.ArticleCacheLo, values.ArticleCacheHi)
-	s.DaySize = joinInt64(values.DaySizeLo, values.DaySizeHi)
-	s.DownloadedSize = joinInt64(values.DownloadedSizeLo, values.DownloadedSizeHi)
-	s.ForcedSize = joinInt64(values.ForcedSizeLo, values.ForcedSizeHi)
-	s.FreeDiskSpace = joinInt64(values.FreeDiskSpaceLo, values.FreeDiskSpaceHi)
-	s.MonthSize = joinInt64(values.MonthSizeLo, values.MonthSizeHi)
-	s.RemainingSize = joinInt64(values.RemainingSizeLo, values.RemainingSizeHi)
+	s.ArticleCache = pickInt64(values.ArticleCache, values.ArticleCacheLo, values.ArticleCacheHi)
+	s.DaySize = pickInt64(values.DaySize, values.DaySizeLo, values.DaySizeHi)
+	s.DownloadedSize = pickInt64(values.DownloadedSize, values.DownloadedSizeLo, values.DownloadedSizeHi)
+	s.ForcedSize = pickInt64(values.ForcedSize, values.ForcedSizeLo, values.ForcedSizeHi)
+	s.FreeDiskSpace = pickInt64(values.FreeDiskSpace, values.FreeDiskSpaceLo, values.FreeDiskSpaceHi)
+	s.MonthSize = pickInt64(values.MonthSize, values.MonthSizeLo, values.MonthSizeHi)
+	s.RemainingSize = pickInt64(values.RemainingSize, values.RemainingSizeLo, values.RemainingSizeHi)
+
+	// The un-split rates overflow above 2GiB/s, so prefer the split ones
+	s.DownloadRate = pickInt64(&s.DownloadRate, values.DownloadRateLo, values.DownloadRateHi)
+	s.AverageDownloadRate = pickInt64(&s.AverageDownloadRate, values.AverageDownloadRateLo, values.AverageDownloadRateHi)
+	s.TotalDiskSpace = pickInt64(nil, values.TotalDiskSpaceLo, values.TotalDiskSpaceHi)
+	s.FreeInterDiskSpace = pickInt64(nil, values.FreeInterDiskSpaceLo, values.FreeInterDiskSpaceHi)
+	s.TotalInterDiskSpace = pickInt64(nil, values.TotalInterDiskSpaceLo, values.TotalInterDiskSpaceHi)
 
 	s.ServerTime = time.Unix(values.ServerTime, 0)
 	s.ResumeTime = time.Unix(values.ResumeTime, 0)
@@ -211,6 +256,7 @@ type NZBGetConfig struct {
 	WriteBuffer       int
 	WriteLog          bool
 	Server            []ConfigServer
+	Category          []ConfigCategory
 }
 
 type ConfigServer struct {
@@ -226,8 +272,28 @@ type ConfigServer struct {
 	Name        string
 	Notes       string
 	Optional    bool
+	Password    string
 	Port        uint16
 	Retention   int
+	Username    string
+}
+
+// FindServer returns the 1-indexed ID of the news server matching key, which
+// may be either the server name or its numeric ID. IDs of servers that aren't
+// configured, such as gaps in the numbering, aren't found
+func (c *NZBGetConfig) FindServer(key string) (int, *ConfigServer) {
+	if id, err := strconv.Atoi(key); err == nil {
+		if id > 0 && id <= len(c.Server) && c.Server[id-1].Host != "" {
+			return id, &c.Server[id-1]
+		}
+		return 0, nil
+	}
+	for i := range c.Server {
+		if strings.EqualFold(c.Server[i].Name, key) {
+			return i + 1, &c.Server[i]
+		}
+	}
+	return 0, nil
 }
 
 type ConfigCategory struct {
@@ -245,7 +311,18 @@ func (c *NZBGetConfig) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	// Slices are grown in powers of two, so remember how many elements were
+	// actually set to trim the padding off afterwards
+	lengths := map[string]int{}
+
 	for _, val := range values {
+		// nzbget-ng stores extension options as 'Extension:Option'. These may
+		// hold credentials, and could be mistaken for server or category
+		// options, so are skipped
+		if strings.Contains(val.Name, ":") {
+			continue
+		}
+
 		of := reflect.ValueOf(c)
 		field := reflect.Indirect(of).FieldByName(val.Name)
 
@@ -280,6 +357,7 @@ func (c *NZBGetConfig) UnmarshalJSON(b []byte) error {
 				reflect.Copy(bigger, slice)
 				slice.Set(bigger)
 			}
+			lengths[structName] = max(lengths[structName], key+1)
 			nthElem := slice.Index(key)
 			// TODO: Handle nthElem being nil-able type
 			field = reflect.Indirect(nthElem).FieldByName(fieldName)
@@ -288,6 +366,11 @@ func (c *NZBGetConfig) UnmarshalJSON(b []byte) error {
 		reflectInto(field, val.Value)
 	}
 
+	for structName, length := range lengths {
+		slice := reflect.Indirect(reflect.ValueOf(c)).FieldByName(structName)
+		slice.Set(slice.Slice(0, length))
+	}
+
 	return nil
 }
 
@@ -346,6 +429,10 @@ type ServerVolume struct {
 	TotalBytes          int64 `json:"-"`
 	TotalArticleSuccess int   `json:"-"`
 	TotalArticleFailed  int   `json:"-"`
+
+	// FirstDay is the day number (days since the epoch) of BytesPerDay[0]
+	FirstDay    int     `json:"-"`
+	BytesPerDay []int64 `json:"-"`
 }
 
 func (v *ServerVolume) UnmarshalJSON(b []byte) error {
@@ -355,11 +442,20 @@ func (v *ServerVolume) UnmarshalJSON(b []byte) error {
 		Failed  int `json:"Failed"`
 	}
 
+	type BytesPerDay struct {
+		Size   *int64  `json:"Size"`
+		SizeLo *uint32 `json:"SizeLo"`
+		SizeHi *uint32 `json:"SizeHi"`
+	}
+
 	type temp struct {
 		ServerID        int             `json:"ServerID"`
-		TotalSizeLo     uint32          `json:"TotalSizeLo"`
-		TotalSizeHi     uint32          `json:"TotalSizeHi"`
+		TotalSize       *int64          `json:"TotalSize"`
+		TotalSizeLo     *uint32         `json:"TotalSizeLo"`
+		TotalSizeHi     *uint32         `json:"TotalSizeHi"`
 		ArticlesPerDays []ArticlePerDay `json:"ArticlesPerDays"`
+		FirstDay        int             `json:"FirstDay"`
+		BytesPerDays    []BytesPerDay   `json:"BytesPerDays"`
 	}
 
 	values := temp{}
@@ -369,7 +465,7 @@ func (v *ServerVolume) UnmarshalJSON(b []byte) error {
 	}
 
 	v.ID = values.ServerID
-	v.TotalBytes = joinInt64(values.TotalSizeLo, values.TotalSizeHi)
+	v.TotalBytes = pickInt64(values.TotalSize, values.TotalSizeLo, values.TotalSizeHi)
 
 	var totalSuccess, totalFailed int
 	for _, day := range values.ArticlesPerDays {
@@ -380,5 +476,11 @@ func (v *ServerVolume) UnmarshalJSON(b []byte) error {
 	v.TotalArticleSuccess = totalSuccess
 	v.TotalArticleFailed = totalFailed
 
+	v.FirstDay = values.FirstDay
+	v.BytesPerDay = make([]int64, len(values.BytesPerDays))
+	for i, day := range values.BytesPerDays {
+		v.BytesPerDay[i] = pickInt64(day.Size, day.SizeLo, day.SizeHi)
+	}
+
 	return nil
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// healthCriticalBuckets are the health to critical health ratio histogram
+// buckets. Items below 1 are beyond repair, and those just above it only just
+// had enough par blocks
+var healthCriticalBuckets = []float64{
+	0.5, 0.8, 0.9, 0.95, 1, 1.01, 1.02, 1.05, 1.1, 1.2, 1.5, 2,
+}
+
+type parStats struct {
+	checked      uint64
+	repairNeeded uint64
+}
+
+func (s *parStats) add(repairNeeded bool) {
+	s.checked++
+	if repairNeeded {
+		s.repairNeeded++
+	}
+}
+
+// needsRepair returns true if par-check found damage in a history item,
+// whether or not it could be repaired
+func (h *History) needsRepair() bool {
+	switch h.ParStatus {
+	case ParStatusFailure, ParStatusRepairPossible:
+		return true
+	}
+	return h.RepairTimeSec > 0
+}
+
+// collectPar exports par2 verification and repair of history items, per
+// category and per news server
+func (c *NZBGetCollector) collectPar(metrics chan<- prom.Metric, config *NZBGetConfig, history []History, hidden string) {
+	var (
+		phaseTime     = map[string]map[string]uint64{}
+		extraBlocks   = map[string]map[string]uint64{}
+		categoryStats = map[string]*parStats{}
+		serverStats   = map[int]*parStats{}
+		health        = newHistogramVec(healthCriticalBuckets)
+	)
+
+	for _, hi := range history {
+		if phaseTime[hi.Category] == nil {
+			phaseTime[hi.Category] = map[string]uint64{}
+			extraBlocks[hi.Category] = map[string]uint64{}
+		}
+		// ParTimeSec includes the time spent repairing
+		if hi.ParTimeSec > hi.RepairTimeSec {
+			phaseTime[hi.Category]["verify"] += hi.ParTimeSec - hi.RepairTimeSec
+		}
+		phaseTime[hi.Category]["repair"] += hi.RepairTimeSec
+
+		if hi.ExtraParBlocks > 0 {
+			extraBlocks[hi.Category]["received"] += uint64(hi.ExtraParBlocks)
+		} else {
+			extraBlocks[hi.Category]["donated"] += uint64(-hi.ExtraParBlocks)
+		}
+
+		if hi.CriticalHealth > 0 {
+			health.observe(float64(hi.Health)/float64(hi.CriticalHealth), withHidden(hidden, hi.Category)...)
+		}
+
+		if hi.ParStatus == ParStatusNone || hi.ParStatus == ParStatusUnknown {
+			continue
+		}
+		repairNeeded := hi.needsRepair()
+
+		st := categoryStats[hi.Category]
+		if st == nil {
+			st = &parStats{}
+			categoryStats[hi.Category] = st
+		}
+		st.add(repairNeeded)
+
+		for _, srv := range hi.ServerStats {
+			if srv.ServerID < 1 || srv.ServerID > len(config.Server) {
+				continue
+			}
+			if srv.SuccessArticles+srv.FailedArticles == 0 {
+				continue
+			}
+			st := serverStats[srv.ServerID]
+			if st == nil {
+				st = &parStats{}
+				serverStats[srv.ServerID] = st
+			}
+			st.add(repairNeeded)
+		}
+	}
+
+	sendConstMapMapMetric(metrics, c.historyParPhaseTime, prom.CounterValue, phaseTime, withHidden(hidden)...)
+	sendConstMapMapMetric(metrics, c.historyParExtraBlocks, prom.CounterValue, extraBlocks, withHidden(hidden)...)
+	health.send(metrics, c.historyParHealthCritical)
+
+	for category, st := range categoryStats {
+		metrics <- prom.MustNewConstMetric(c.historyParCheckedCount, prom.CounterValue, float64(st.checked), withHidden(hidden, category)...)
+		metrics <- prom.MustNewConstMetric(c.historyParRepairCount, prom.CounterValue, float64(st.repairNeeded), withHidden(hidden, category)...)
+		metrics <- prom.MustNewConstMetric(c.historyParRepairRatio, prom.GaugeValue, float64(st.repairNeeded)/float64(st.checked), withHidden(hidden, category)...)
+	}
+	for idx, st := range serverStats {
+		id := fmt.Sprintf("%d", idx)
+		name := config.Server[idx-1].Name
+		metrics <- prom.MustNewConstMetric(c.newsServerParCheckedCount, prom.CounterValue, float64(st.checked), withHidden(hidden, id, name)...)
+		metrics <- prom.MustNewConstMetric(c.newsServerParRepairCount, prom.CounterValue, float64(st.repairNeeded), withHidden(hidden, id, name)...)
+		metrics <- prom.MustNewConstMetric(c.newsServerParRepairRatio, prom.GaugeValue, float64(st.repairNeeded)/float64(st.checked), withHidden(hidden, id, name)...)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// labelNameRegex matches valid Prometheus label names, excluding those
+// reserved for internal use
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ParamLabelName is the name of the label set by parameter label rules, which
+// must not clash with the other labels of the history metrics it is added to
+type ParamLabelName string
+
+func (n *ParamLabelName) UnmarshalFlag(value string) error {
+	if !labelNameRegex.MatchString(value) || strings.HasPrefix(value, "__") {
+		return fmt.Errorf("param label name %q is not a valid label name", value)
+	}
+	switch value {
+	case "status", "category", "hidden":
+		return fmt.Errorf("param label name %q is already used by history metrics", value)
+	}
+	*n = ParamLabelName(value)
+	return nil
+}
+
+// ParamLabelRule derives a label value from the parameters of a job,
+// configured as 'param[=regex]:value'. An empty value uses the value of the
+// parameter itself
+type ParamLabelRule struct {
+	Param   string
+	Pattern *regexp.Regexp
+	Value   string
+}
+
+func (r *ParamLabelRule) UnmarshalFlag(value string) error {
+	// Parameter names may themselves contain ':', such as '*Unpack:', so the
+	// label value is after the last one
+	i := strings.LastIndex(value, ":")
+	if i < 0 {
+		return fmt.Errorf("param label %q must be in the form param[=regex]:value", value)
+	}
+	param, pattern, hasPattern := strings.Cut(value[:i], "=")
+	if param == "" {
+		return fmt.Errorf("param label %q: missing parameter name", value)
+	}
+	if hasPattern {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("param label %q: %w", value, err)
+		}
+		r.Pattern = re
+	}
+
+	r.Param = param
+	r.Value = value[i+1:]
+	return nil
+}
+
+// match returns the label value for a job's parameters, if the rule matches
+func (r *ParamLabelRule) match(params []Parameters) (string, bool) {
+	for _, p := range params {
+		if !strings.EqualFold(p.Name, r.Param) {
+			continue
+		}
+		if r.Pattern != nil && !r.Pattern.MatchString(p.Value) {
+			continue
+		}
+		if r.Value == "" {
+			return p.Value, true
+		}
+		return r.Value, true
+	}
+	return "", false
+}
+
+// paramLabelOf returns the label value of the first matching rule, or 'none'
+func (c *NZBGetCollector) paramLabelOf(hi *History) string {
+	for _, rule := range c.Config.ParamLabels {
+		if value, ok := rule.match(hi.Parameters); ok && value != "" {
+			return value
+		}
+	}
+	return "none"
+}
+
+type paramLabelStats struct {
+	statuses     map[string]map[string]uint64
+	size         int64
+	downloadTime uint64
+}
+
+// collectParamLabels exports history items broken down by the label derived
+// from their parameters, such as which *arr client added them
+func (c *NZBGetCollector) collectParamLabels(metrics chan<- prom.Metric, history []History, hidden string) {
+	if len(c.Config.ParamLabels) == 0 {
+		return
+	}
+
+	values := map[string]*paramLabelStats{}
+	for _, hi := range history {
+		value := c.paramLabelOf(&hi)
+		st := values[value]
+		if st == nil {
+			st = &paramLabelStats{statuses: map[string]map[string]uint64{}}
+			values[value] = st
+		}
+
+		status := strings.ToLower(hi.StatusType.String())
+		if st.statuses[hi.Category] == nil {
+			st.statuses[hi.Category] = map[string]uint64{}
+		}
+		st.statuses[hi.Category][status]++
+		st.size += hi.DownloadedSize
+		st.downloadTime += hi.DownloadTimeSec
+	}
+
+	for value, st := range values {
+		sendConstMapMapMetric(metrics, c.historyParamLabelCount, prom.CounterValue, st.statuses, withHidden(hidden, value)...)
+		metrics <- prom.MustNewConstMetric(c.historyParamLabelSize, prom.CounterValue, float64(st.size), withHidden(hidden, value)...)
+		metrics <- prom.MustNewConstMetric(c.historyParamLabelTime, prom.CounterValue, float64(st.downloadTime), withHidden(hidden, value)...)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// defaultProbeTimeout is used when neither the 'timeout' parameter nor the
+// Prometheus scrape timeout header are provided
+const defaultProbeTimeout = 10 * time.Second
+
+// minProbeTimeout is the shortest connection test NZBGet can run, as it takes
+// the timeout in whole seconds
+const minProbeTimeout = time.Second
+
+// probeErrorClasses maps substrings of NZBGet testserver errors to a coarse
+// error class. The first match wins
+var probeErrorClasses = [...]struct {
+	class    string
+	patterns []string
+}{
+	{"auth", []string{"authoriz", "authentic", "password", "username", "login", "481", "482"}},
+	{"tls", []string{"tls", "ssl", "certificate", "handshake", "cipher"}},
+	{"dns", []string{"resolve", "no such host", "host not found", "unknown host"}},
+	{"timeout", []string{"timeout", "timed out"}},
+	{"connection", []string{"connect", "refused", "reset", "unreachable", "closed"}},
+}
+
+func classifyProbeError(msg string) string {
+	msg = strings.ToLower(msg)
+	for _, class := range probeErrorClasses {
+		for _, pattern := range class.patterns {
+			if strings.Contains(msg, pattern) {
+				return class.class
+			}
+		}
+	}
+	return "other"
+}
+
+// ProbeNewsServer handles /probe/newsserver?server=<name|id>, asking NZBGet
+// to test the connection to a configured news server with 'testserver'
+func (c *NZBGetCollector) ProbeNewsServer(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("server")
+	if key == "" {
+		http.Error(w, "'server' parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	timeout := defaultProbeTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, "invalid 'timeout' parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	} else if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		// Leave time to respond before Prometheus gives up on the scrape, so
+		// that a slow server is reported as a failed probe
+		secs, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			timeout = time.Duration(secs*float64(time.Second)) - c.Config.ProbeTimeoutOffset
+		}
+	}
+	timeout = max(timeout, minProbeTimeout)
+
+	ns := c.Config.Namespace
+	probeSuccess := prom.NewGauge(prom.GaugeOpts{
+		Name: prom.BuildFQName(ns, "probe", "success"),
+		Help: "1 if the news server connection test succeeded, 0 otherwise",
+	})
+	probeDuration := prom.NewGauge(prom.GaugeOpts{
+		Name: prom.BuildFQName(ns, "probe", "duration_seconds"),
+		Help: "Total duration of the probe, in seconds",
+	})
+	probeLatency := prom.NewGauge(prom.GaugeOpts{
+		Name: prom.BuildFQName(ns, "probe", "testserver_duration_seconds"),
+		Help: "Duration of the NZBGet 'testserver' call, in seconds",
+	})
+	probeError := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: prom.BuildFQName(ns, "probe", "error"),
+		Help: "1 for the class of error that caused the probe to fail",
+	}, []string{"class"})
+
+	registry := prom.NewRegistry()
+	registry.MustRegister(probeSuccess, probeDuration, probeLatency, probeError)
+
+	start := time.Now()
+	logger := log.WithField("server", key)
+
+	class := c.probeNewsServer(key, timeout, probeLatency)
+	if class == "" {
+		probeSuccess.Set(1)
+		logger.Debug("probe news server succeeded")
+	} else {
+		probeError.WithLabelValues(class).Set(1)
+		logger.WithField("class", class).Info("probe news server failed")
+	}
+	probeDuration.Set(time.Since(start).Seconds())
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// probeNewsServer runs the connection test, returning the error class on
+// failure or an empty string on success
+func (c *NZBGetCollector) probeNewsServer(key string, timeout time.Duration, latency prom.Gauge) string {
+	var config NZBGetConfig
+	err := c.getApi("config", &config)
+	if err != nil {
+		log.WithError(err).Error("api get config")
+		return "api"
+	}
+	_, server := config.FindServer(key)
+	if server == nil {
+		log.WithField("server", key).Warn("probe for unknown news server")
+		return "unknown_server"
+	}
+
+	// NZBGet takes the timeout in whole seconds, so round down to stay within
+	// the timeout
+	timeoutSec := int(timeout / time.Second)
+
+	// testserver returns an empty string on success, or the error message
+	var result string
+	start := time.Now()
+	err = c.callApi("testserver", []interface{}{
+		server.Host,
+		server.Port,
+		server.Username,
+		server.Password,
+		server.Encryption,
+		server.Cipher,
+		timeoutSec,
+	}, &result)
+	latency.Set(time.Since(start).Seconds())
+	if err != nil {
+		log.WithError(err).Error("api call testserver")
+		return "api"
+	}
+	if result != "" {
+		log.WithField("server", server.Name).
+			WithField("error", result).
+			Debug("testserver failed")
+		return classifyProbeError(result)
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Group is an item in the download queue, as returned by 'listgroups'
+// https://nzbget.net/api/listgroups
+type Group struct {
+	NZBID              int
+	NZBName            string
+	Kind               string
+	URL                string
+	Category           string
+	Status             string
+	FileSize           int64
+	RemainingSize      int64
+	FileCount          uint64
+	RemainingFileCount uint64
+	TotalArticles      uint64
+	SuccessArticles    uint64
+	FailedArticles     uint64
+	Health             uint64
+	CriticalHealth     uint64
+	ActiveDownloads    int
+	MinPostTime        time.Time `json:"-"`
+	MaxPostTime        time.Time `json:"-"`
+}
+
+func (g *Group) UnmarshalJSON(b []byte) error {
+	type resultClone Group
+	err := json.Unmarshal(b, (*resultClone)(g))
+	if err != nil {
+		return err
+	}
+
+	type temp struct {
+		FileSizeHi      *uint32 `json:"FileSizeHi"`
+		FileSizeLo      *uint32 `json:"FileSizeLo"`
+		RemainingSizeHi *uint32 `json:"RemainingSizeHi"`
+		RemainingSizeLo *uint32 `json:"RemainingSizeLo"`
+
+		MinPostTime int64 `json:"MinPostTime"`
+		MaxPostTime int64 `json:"MaxPostTime"`
+	}
+
+	values := temp{}
+	err = json.Unmarshal(b, &values)
+	if err != nil {
+		return err
+	}
+
+	g.FileSize = pickInt64(&g.FileSize, values.FileSizeLo, values.FileSizeHi)
+	g.RemainingSize = pickInt64(&g.RemainingSize, values.RemainingSizeLo, values.RemainingSizeHi)
+	g.MinPostTime = time.Unix(values.MinPostTime, 0)
+	g.MaxPostTime = time.Unix(values.MaxPostTime, 0)
+
+	return nil
+}
+
+// maxHealthJobs is the most queue items exported with their health headroom,
+// as each one is its own series that disappears when the job completes
+const maxHealthJobs = 50
+
+// collectQueue exports the health of items in the download queue, flagging
+// those close to their critical health before NZBGet deletes them for health
+func (c *NZBGetCollector) collectQueue(metrics chan<- prom.Metric, groups []Group) {
+	var (
+		health       = newHistogramVec(healthBuckets)
+		nearCritical = map[string]uint64{}
+		nearGroups   []Group
+	)
+
+	for _, g := range groups {
+		// URLs have no articles until the nzb is fetched
+		if g.Kind == "URL" || g.TotalArticles == 0 {
+			continue
+		}
+		health.observe(float64(g.Health)/1000, g.Category)
+
+		if _, ok := nearCritical[g.Category]; !ok {
+			nearCritical[g.Category] = 0
+		}
+		if !nearCriticalHealth(g.Health, g.CriticalHealth, c.Config.HealthMargin) {
+			continue
+		}
+		nearCritical[g.Category]++
+		nearGroups = append(nearGroups, g)
+
+		log.WithField("nzbid", g.NZBID).
+			WithField("name", g.NZBName).
+			WithField("health", g.Health).
+			WithField("critical", g.CriticalHealth).
+			Debug("queue item near critical health")
+	}
+
+	health.send(metrics, c.queueHealth)
+	sendConstMapMetric(metrics, c.queueHealthNearCritical, prom.GaugeValue, nearCritical)
+
+	// Only the jobs nearest failing are exported individually
+	limit := min(c.Config.HealthJobs, maxHealthJobs)
+	if limit <= 0 {
+		return
+	}
+	sort.Slice(nearGroups, func(i, j int) bool {
+		return nearGroups[i].Health-nearGroups[i].CriticalHealth < nearGroups[j].Health-nearGroups[j].CriticalHealth
+	})
+	if len(nearGroups) > limit {
+		nearGroups = nearGroups[:limit]
+	}
+	for _, g := range nearGroups {
+		headroom := (float64(g.Health) - float64(g.CriticalHealth)) / 1000
+		metrics <- prom.MustNewConstMetric(c.queueHealthHeadroom, prom.GaugeValue, headroom,
+			fmt.Sprintf("%d", g.NZBID), c.recentFailureName(g.NZBName), g.Category, strings.ToLower(g.Status))
+	}
+}
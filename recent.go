@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// maxRecentFailures caps the number of recent failure series, whatever is
+// configured, to keep cardinality bounded
+const maxRecentFailures = 50
+
+// recentFailureName returns the job name as configured to be exported
+func (c *NZBGetCollector) recentFailureName(name string) string {
+	switch c.Config.RecentFailuresNames {
+	case "hash":
+		sum := sha256.Sum256([]byte(name))
+		return hex.EncodeToString(sum[:])[:12]
+	case "redact":
+		return "redacted"
+	}
+	return name
+}
+
+// collectRecentFailures exports an info series for each of the most recent
+// failed or deleted history items, so alerts and dashboards can show which
+// jobs failed
+func (c *NZBGetCollector) collectRecentFailures(metrics chan<- prom.Metric, history []History) {
+	limit := min(c.Config.RecentFailures, maxRecentFailures)
+	if limit <= 0 {
+		return
+	}
+
+	since := time.Now().Add(-c.Config.RecentFailuresWindow)
+	var failed []History
+	for _, hi := range history {
+		if hi.Hidden() || hi.HistoryTime.Before(since) {
+			continue
+		}
+		if hi.StatusType == HistoryStatusFailure || hi.StatusType == HistoryStatusDeleted {
+			failed = append(failed, hi)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].HistoryTime.After(failed[j].HistoryTime)
+	})
+	if len(failed) > limit {
+		failed = failed[:limit]
+	}
+
+	for _, hi := range failed {
+		metrics <- prom.MustNewConstMetric(c.historyRecentFailure, prom.GaugeValue, 1,
+			fmt.Sprintf("%d", hi.NZBID), c.recentFailureName(hi.Name), hi.Category, strings.ToLower(hi.Status))
+	}
+}
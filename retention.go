@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+type retentionKey struct {
+	serverID int
+	hidden   string
+}
+
+type retentionStats struct {
+	exceeded uint64
+	// articles indexed by [outside retention][failed]
+	articles [2][2]uint64
+}
+
+// collectRetention compares the post age of each history item with the
+// configured retention of the news servers that served it, to show when a
+// server is asked for articles older than it claims to keep
+func (c *NZBGetCollector) collectRetention(metrics chan<- prom.Metric, config *NZBGetConfig, history []History) {
+	stats := map[retentionKey]*retentionStats{}
+
+	for _, hi := range history {
+		if hi.MinPostTime.Unix() <= 0 || !hi.HistoryTime.After(hi.MinPostTime) {
+			continue
+		}
+		ageDays := int(hi.HistoryTime.Sub(hi.MinPostTime).Seconds() / day)
+
+		for _, srv := range hi.ServerStats {
+			if srv.ServerID < 1 || srv.ServerID > len(config.Server) {
+				continue
+			}
+			if srv.SuccessArticles+srv.FailedArticles == 0 {
+				continue
+			}
+			key := retentionKey{srv.ServerID, c.hiddenLabel(&hi)}
+			st := stats[key]
+			if st == nil {
+				st = &retentionStats{}
+				stats[key] = st
+			}
+
+			// Retention of 0 means unlimited
+			retention := config.Server[srv.ServerID-1].Retention
+			outside := 0
+			if retention > 0 && ageDays > retention {
+				outside = 1
+				st.exceeded++
+			}
+			st.articles[outside][0] += uint64(srv.SuccessArticles)
+			st.articles[outside][1] += uint64(srv.FailedArticles)
+		}
+	}
+
+	for idx, server := range config.Server {
+		if server.Name == "" {
+			continue
+		}
+		id := fmt.Sprintf("%d", idx+1)
+		metrics <- prom.MustNewConstMetric(c.newsServerRetention, prom.GaugeValue, float64(server.Retention), id, server.Name)
+	}
+
+	for key, st := range stats {
+		id := fmt.Sprintf("%d", key.serverID)
+		name := config.Server[key.serverID-1].Name
+
+		metrics <- prom.MustNewConstMetric(c.newsServerRetentionExceeded, prom.CounterValue, float64(st.exceeded), withHidden(key.hidden, id, name)...)
+		for outside, retention := range [...]string{"inside", "outside"} {
+			success := st.articles[outside][0]
+			failed := st.articles[outside][1]
+			metrics <- prom.MustNewConstMetric(c.newsServerRetentionSuccess, prom.CounterValue, float64(success), withHidden(key.hidden, id, name, retention)...)
+			metrics <- prom.MustNewConstMetric(c.newsServerRetentionFailed, prom.CounterValue, float64(failed), withHidden(key.hidden, id, name, retention)...)
+			if success+failed > 0 {
+				ratio := float64(failed) / float64(success+failed)
+				metrics <- prom.MustNewConstMetric(c.newsServerRetentionFailure, prom.GaugeValue, ratio, withHidden(key.hidden, id, name, retention)...)
+			}
+		}
+	}
+}
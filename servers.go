@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// newsServerState tracks the Active flag of a news server between polls, so
+// that short-lived deactivations between scrapes are still accounted for
+type newsServerState struct {
+	active     bool
+	lastSeen   time.Time
+	transition time.Time
+	changes    uint64
+	inactive   time.Duration
+}
+
+func (s *newsServerState) observe(now time.Time, active bool) {
+	// The poller and scrapes fetch status concurrently, so a status fetched
+	// before the last one observed may arrive after it. Applying it would
+	// count time backwards and record a false flap. now is when the status
+	// was requested rather than received, so that a slow response is still
+	// ordered by when it was fetched
+	if now.Before(s.lastSeen) {
+		return
+	}
+	if !s.active {
+		s.inactive += now.Sub(s.lastSeen)
+	}
+	if active != s.active {
+		s.changes++
+		s.transition = now
+	}
+	s.active = active
+	s.lastSeen = now
+}
+
+// observeNewsServers records the Active state of each news server
+func (c *NZBGetCollector) observeNewsServers(now time.Time, servers []NewsServers) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, srv := range servers {
+		state := c.newsServerStates[srv.ID]
+		if state == nil {
+			// First time we've seen this server, nothing to compare against
+			c.newsServerStates[srv.ID] = &newsServerState{
+				active:   srv.Active,
+				lastSeen: now,
+			}
+			continue
+		}
+		state.observe(now, srv.Active)
+	}
+}
+
+// PollNewsServers polls the status endpoint every interval to catch news
+// server deactivations that are shorter than the scrape interval
+func (c *NZBGetCollector) PollNewsServers(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var status Status
+		requested := time.Now()
+		err := c.getApi("status", &status)
+		if err != nil {
+			log.WithError(err).Debug("poll news server status")
+			continue
+		}
+		c.observeNewsServers(requested, status.NewsServers)
+	}
+}
+
+func (c *NZBGetCollector) collectNewsServerStates(metrics chan<- prom.Metric, config *NZBGetConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for idx, state := range c.newsServerStates {
+		if idx < 1 || idx > len(config.Server) {
+			continue
+		}
+		id := fmt.Sprintf("%d", idx)
+		name := config.Server[idx-1].Name
+
+		metrics <- prom.MustNewConstMetric(c.newsServerStateChanges, prom.CounterValue, float64(state.changes), id, name)
+		metrics <- prom.MustNewConstMetric(c.newsServerInactiveTime, prom.CounterValue, state.inactive.Seconds(), id, name)
+		if !state.transition.IsZero() {
+			metrics <- prom.MustNewConstMetric(c.newsServerLastTransition, prom.GaugeValue, float64(state.transition.Unix()), id, name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// sysinfo is only available in nzbget-ng. It has no numeric fields, so is only
+// exported as info series
+
+type SysInfo struct {
+	OS struct {
+		Name    string
+		Version string
+	}
+	CPU struct {
+		Model string
+		Arch  string
+	}
+	Network struct {
+		PublicIP  string
+		PrivateIP string
+	}
+	Tools     []SysInfoTool
+	Libraries []SysInfoLibrary
+}
+
+type SysInfoTool struct {
+	Name    string
+	Version string
+	Path    string
+}
+
+type SysInfoLibrary struct {
+	Name    string
+	Version string
+}
+
+func (c *NZBGetCollector) collectSysInfo(metrics chan<- prom.Metric) {
+	var info SysInfo
+	err := c.getApi("sysinfo", &info)
+	if err != nil {
+		// Best effort, as the host details are only informational
+		log.WithError(err).Error("api get sysinfo")
+		return
+	}
+
+	metrics <- prom.MustNewConstMetric(c.sysInfo, prom.GaugeValue, 1,
+		info.OS.Name, info.OS.Version, info.CPU.Model, info.CPU.Arch)
+	metrics <- prom.MustNewConstMetric(c.sysInfoNetwork, prom.GaugeValue, 1,
+		info.Network.PrivateIP, info.Network.PublicIP)
+	for _, tool := range info.Tools {
+		metrics <- prom.MustNewConstMetric(c.sysInfoTool, prom.GaugeValue, 1, tool.Name, tool.Version)
+		metrics <- prom.MustNewConstMetric(c.sysInfoToolAvailable, prom.GaugeValue, floatOf(tool.Path != ""), tool.Name)
+	}
+	for _, lib := range info.Libraries {
+		metrics <- prom.MustNewConstMetric(c.sysInfoLibrary, prom.GaugeValue, 1, lib.Name, lib.Version)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+// historyTracker remembers which history items have already been seen, so
+// counters can be incremented exactly once as each job completes
+type historyTracker struct {
+	seeded bool
+	seen   map[uint64]bool
+}
+
+// completed returns the history items that weren't present last time. The
+// first call only records the existing history, as those jobs completed
+// before the exporter started
+func (t *historyTracker) completed(history []History) []History {
+	var completed []History
+	seen := make(map[uint64]bool, len(history))
+	for _, hi := range history {
+		seen[hi.NZBID] = true
+		if t.seeded && !t.seen[hi.NZBID] {
+			completed = append(completed, hi)
+		}
+	}
+	// Replace rather than add to the seen items so that items removed from
+	// history are forgotten
+	t.seen = seen
+	t.seeded = true
+	return completed
+}
+
+// completedHistory returns the history items that completed since the last
+// scrape
+func (c *NZBGetCollector) completedHistory(history []History) []History {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.historyTracker.completed(history)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// indexerHost returns the host of an indexer url, never the path or query as
+// those usually contain the api key
+func indexerHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "unknown"
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+type urlFetchKey struct {
+	status   string
+	category string
+	host     string
+}
+
+// collectURLHistory exports the outcome of fetching nzbs from indexer urls
+// for history items
+func (c *NZBGetCollector) collectURLHistory(metrics chan<- prom.Metric, history []History, hidden string) {
+	fetches := map[urlFetchKey]uint64{}
+	for _, hi := range history {
+		if hi.URLStatus == URLStatusNone {
+			continue
+		}
+		key := urlFetchKey{
+			status:   strings.ToLower(hi.URLStatus.String()),
+			category: hi.Category,
+			host:     indexerHost(hi.URL),
+		}
+		fetches[key]++
+	}
+
+	for key, count := range fetches {
+		metrics <- prom.MustNewConstMetric(c.historyURLFetchCount, prom.CounterValue, float64(count),
+			withHidden(hidden, key.status, key.category, key.host)...)
+	}
+}
+
+// collectURLQueue exports the urls waiting to be fetched. NZBGet doesn't
+// report when urls were added, so the age is from when the exporter first saw
+// each url in the queue
+func (c *NZBGetCollector) collectURLQueue(metrics chan<- prom.Metric, groups []Group) {
+	now := time.Now()
+	queued := map[string]map[string]uint64{}
+	var oldest time.Time
+
+	c.mu.Lock()
+	firstSeen := make(map[int]time.Time, len(c.urlQueueSeen))
+	for _, g := range groups {
+		if g.Kind != "URL" {
+			continue
+		}
+		seen, ok := c.urlQueueSeen[g.NZBID]
+		if !ok {
+			seen = now
+		}
+		// Replace rather than add to the seen urls so that fetched urls are
+		// forgotten
+		firstSeen[g.NZBID] = seen
+		if oldest.IsZero() || seen.Before(oldest) {
+			oldest = seen
+		}
+
+		status := strings.ToLower(g.Status)
+		if queued[status] == nil {
+			queued[status] = map[string]uint64{}
+		}
+		queued[status][indexerHost(g.URL)]++
+	}
+	c.urlQueueSeen = firstSeen
+	c.mu.Unlock()
+
+	var age float64
+	if !oldest.IsZero() {
+		age = now.Sub(oldest).Seconds()
+	}
+	metrics <- prom.MustNewConstMetric(c.queueURLOldestAge, prom.GaugeValue, age)
+	sendConstMapMapMetric(metrics, c.queueURLCount, prom.GaugeValue, queued)
+}
@@ -1,6 +1,14 @@
 package main
 
-import "strings"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// day is the number of seconds in a day
+const day = 24 * 60 * 60
 
 func floatOf(b bool) float64 {
 	if b {
@@ -25,3 +33,72 @@ func getBool(s string) bool {
 func joinInt64(lo, hi uint32) int64 {
 	return (int64(hi) << 32) + int64(lo)
 }
+
+// parseBytes parses a human-readable size such as "500GB" or "1TiB" into a
+// number of bytes. SI suffixes are powers of 1000, IEC suffixes powers of 1024
+func parseBytes(s string) (int64, error) {
+	var units = [...]struct {
+		suffix string
+		scale  float64
+	}{
+		{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30}, {"tib", 1 << 40},
+		{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9}, {"tb", 1e12},
+		{"k", 1e3}, {"m", 1e6}, {"g", 1e9}, {"t", 1e12},
+		{"b", 1},
+	}
+	str := strings.ToLower(strings.TrimSpace(s))
+	scale := 1.0
+	for _, unit := range units {
+		if strings.HasSuffix(str, unit.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, unit.suffix))
+			scale = unit.scale
+			break
+		}
+	}
+	val, err := strconv.ParseFloat(str, 64)
+	if err != nil || val < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(val * scale), nil
+}
+
+// pickInt64 returns the Hi/Lo halves joined if present, otherwise the plain
+// 64-bit value if present, otherwise 0
+func pickInt64(plain *int64, lo, hi *uint32) int64 {
+	if lo != nil && hi != nil {
+		return joinInt64(*lo, *hi)
+	}
+	if plain != nil {
+		return *plain
+	}
+	return 0
+}
+
+// nearCriticalHealth returns true if health (per-mille) is damaged but still
+// within margin (per-mille) of critical health, below which the download can't
+// be repaired
+func nearCriticalHealth(health, critical uint64, margin int) bool {
+	if health >= 1000 || health < critical {
+		return false
+	}
+	return health-critical <= uint64(margin)
+}
+
+// parseDuration parses a duration like time.ParseDuration, but also accepts a
+// whole number of days or weeks, e.g. '7d' or '2w'
+func parseDuration(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": day * time.Second,
+		"w": 7 * day * time.Second,
+	}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// ServerVersion is the major and minor version of the NZBGet server, as
+// reported by the 'version' method, e.g. '21.1' or '24.3-testing-20240812'
+type ServerVersion struct {
+	Raw   string
+	Major int
+	Minor int
+}
+
+var versionRegex = regexp.MustCompile(`^(\d+)(?:\.(\d+))?`)
+
+func parseVersion(s string) ServerVersion {
+	version := ServerVersion{Raw: s}
+	groups := versionRegex.FindStringSubmatch(s)
+	if len(groups) < 3 {
+		return version
+	}
+	version.Major, _ = strconv.Atoi(groups[1])
+	version.Minor, _ = strconv.Atoi(groups[2])
+	return version
+}
+
+func (v ServerVersion) AtLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+// Feature is an api method or set of fields only available in newer NZBGet
+// versions. The community fork nzbget-ng continues versioning from v22.
+//
+// Features decide which api methods are called and which optional metrics are
+// exported. Responses are decoded by which fields are present rather than by
+// version, such as the split Hi/Lo download rates added in v22, so a
+// misreported or patched version can't cause values to be misread
+type Feature string
+
+const (
+	// FeatureDiskTotals is total disk space, and space of InterDir, in status
+	FeatureDiskTotals Feature = "disk_totals"
+	// FeatureExtensions is the 'loadextensions' method
+	FeatureExtensions Feature = "extensions"
+	// FeatureSysInfo is the 'sysinfo' method
+	FeatureSysInfo Feature = "sysinfo"
+	// FeatureBenchmarks is the 'testserverspeed' and 'testdiskspeed' methods
+	FeatureBenchmarks Feature = "benchmarks"
+	// FeatureSystemHealth is the 'systemhealth' method
+	FeatureSystemHealth Feature = "systemhealth"
+)
+
+// featureVersions is the minimum NZBGet version for each api feature
+var featureVersions = []struct {
+	feature      Feature
+	major, minor int
+}{
+	{FeatureDiskTotals, 23, 0},
+	{FeatureExtensions, 23, 0},
+	{FeatureSysInfo, 24, 0},
+	{FeatureBenchmarks, 24, 0},
+	{FeatureSystemHealth, 25, 0},
+}
+
+// setVersion records the NZBGet server version, logging when it changes such
+// as after NZBGet is upgraded
+func (c *NZBGetCollector) setVersion(version string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.serverVersion.Raw == version {
+		return
+	}
+	if c.serverVersion.Raw != "" {
+		log.WithField("old", c.serverVersion.Raw).
+			WithField("new", version).
+			Info("nzbget version changed")
+	}
+	c.serverVersion = parseVersion(version)
+}
+
+// fetchVersion gets and records the NZBGet server version
+func (c *NZBGetCollector) fetchVersion() (string, error) {
+	var version string
+	err := c.getApi("version", &version)
+	if err != nil {
+		return "", err
+	}
+	c.setVersion(version)
+	return version, nil
+}
+
+// HasFeature returns true if the NZBGet server is known to support feature
+func (c *NZBGetCollector) HasFeature(feature Feature) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, f := range featureVersions {
+		if f.feature == feature {
+			return c.serverVersion.AtLeast(f.major, f.minor)
+		}
+	}
+	return false
+}
+
+func (c *NZBGetCollector) collectFeatures(metrics chan<- prom.Metric) {
+	for _, f := range featureVersions {
+		available := floatOf(c.HasFeature(f.feature))
+		metrics <- prom.MustNewConstMetric(c.apiFeature, prom.GaugeValue, available, string(f.feature))
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// HistoryWindow is a period of recent history to export statistics for,
+// configured as a duration such as '1h', '24h' or '7d'
+type HistoryWindow struct {
+	Name     string
+	Duration time.Duration
+}
+
+func (w *HistoryWindow) UnmarshalFlag(value string) error {
+	duration, err := parseDuration(value)
+	if err != nil {
+		return fmt.Errorf("history window: %w", err)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("history window %q must be positive", value)
+	}
+	w.Name = value
+	w.Duration = duration
+	return nil
+}
+
+type windowStats struct {
+	statuses map[string]uint64
+	size     int64
+}
+
+// collectHistoryWindows exports completion statistics of history items that
+// reached history within each window.
+// Unlike the other history metrics these don't depend on how long NZBGet
+// keeps history for
+func (c *NZBGetCollector) collectHistoryWindows(metrics chan<- prom.Metric, history []History, hidden string) {
+	now := time.Now()
+	for _, window := range c.Config.HistoryWindows {
+		since := now.Add(-window.Duration)
+		categories := map[string]*windowStats{}
+		for _, hi := range history {
+			// Unknown statuses can't be told apart as a success or failure,
+			// so are left out of both the counts and the size
+			if hi.HistoryTime.Before(since) || hi.StatusType == HistoryStatusUnknown {
+				continue
+			}
+			st := categories[hi.Category]
+			if st == nil {
+				st = &windowStats{statuses: map[string]uint64{
+					"completed": 0,
+					"warning":   0,
+					"failed":    0,
+					"deleted":   0,
+				}}
+				categories[hi.Category] = st
+			}
+
+			switch hi.StatusType {
+			case HistoryStatusSuccess:
+				st.statuses["completed"]++
+			case HistoryStatusWarning:
+				// Such as WARNING/PASSWORD or WARNING/DAMAGED, which aren't
+				// usable downloads
+				st.statuses["warning"]++
+			case HistoryStatusFailure:
+				st.statuses["failed"]++
+			case HistoryStatusDeleted:
+				st.statuses["deleted"]++
+			}
+			st.size += hi.DownloadedSize
+		}
+
+		for category, st := range categories {
+			sendConstMapMetric(metrics, c.historyWindowCount, prom.GaugeValue, st.statuses, withHidden(hidden, category, window.Name)...)
+			metrics <- prom.MustNewConstMetric(c.historyWindowSize, prom.GaugeValue, float64(st.size), withHidden(hidden, category, window.Name)...)
+
+			var total uint64
+			for _, count := range st.statuses {
+				total += count
+			}
+			if total > 0 {
+				ratio := float64(st.statuses["completed"]) / float64(total)
+				metrics <- prom.MustNewConstMetric(c.historyWindowSuccess, prom.GaugeValue, ratio, withHidden(hidden, category, window.Name)...)
+			}
+		}
+	}
+}